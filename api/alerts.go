@@ -0,0 +1,195 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/alerts"
+	"github.com/waplay/picoclaw-dashboard/pkg/audit"
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+	"github.com/waplay/picoclaw-dashboard/websocket"
+)
+
+var alertEngine *alerts.Engine
+
+// AlertsResponse — ответ /api/alerts
+type AlertsResponse struct {
+	Alerts   []alerts.Alert   `json:"alerts"`
+	Silences []alerts.Silence `json:"silences"`
+}
+
+// SilenceRequest — тело POST /api/alerts/silences. Нужно указать duration или until
+type SilenceRequest struct {
+	Rule     string    `json:"rule"` // имя правила или "*"
+	Duration string    `json:"duration,omitempty"`
+	Until    time.Time `json:"until,omitempty"`
+	Comment  string    `json:"comment,omitempty"`
+}
+
+// InitAlerts создаёт движок алертов; смены состояний рассылаются через hub
+func InitAlerts(cfg *config.Config, hub *websocket.Hub) error {
+	engine, err := alerts.NewEngine(cfg)
+	if err != nil {
+		return err
+	}
+	alertEngine = engine
+
+	engine.Subscribe(func(e alerts.Event) {
+		log.Printf("🚨 Alert %s: %s -> %s (%s = %g)", e.Alert.Rule.Name, e.Previous, e.Alert.State, e.Alert.Rule.Metric, e.Alert.Value)
+		hub.Publish(websocket.TopicAlerts, e)
+	})
+
+	if n := len(cfg.Alerts.Rules); n > 0 {
+		log.Printf("🚨 Alerting: %d rule(s)", n)
+	}
+	return nil
+}
+
+// EvaluateHealth прогоняет правила по замеру метрик хоста
+func EvaluateHealth(h HealthResponse) {
+	if alertEngine == nil {
+		return
+	}
+	alertEngine.Evaluate(map[string]float64{
+		"cpu.usage_percent":      h.CPU.Usage,
+		"cpu.cores":              float64(h.CPU.Cores),
+		"memory.used_percent":    h.Memory.UsedPercent,
+		"memory.used_bytes":      float64(h.Memory.Used),
+		"memory.available_bytes": float64(h.Memory.Available),
+		"disk.used_percent":      h.Disk.UsedPercent,
+		"disk.used_bytes":        float64(h.Disk.Used),
+		"disk.free_bytes":        float64(h.Disk.Free),
+		"uptime.seconds":         float64(h.Uptime.Uptime),
+	}, time.Now())
+}
+
+// EvaluateService прогоняет правила по статусу основного сервиса; остальные юниты пропускаются
+func EvaluateService(s ServiceResponse) {
+	if alertEngine == nil || s.Unit != serviceUnit {
+		return
+	}
+	alertEngine.Evaluate(map[string]float64{
+		"service.active":  boolToFloat(s.Active),
+		"service.running": boolToFloat(s.Running),
+		"service.loaded":  boolToFloat(s.Loaded),
+		"service.enabled": boolToFloat(s.Enabled),
+	}, time.Now())
+}
+
+// SetupAlertRoutes — GET /api/alerts, POST/DELETE /api/alerts/silences
+func SetupAlertRoutes() {
+	http.HandleFunc("/api/alerts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !auth.Allow(w, r, auth.PermAlertsRead) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AlertsResponse{
+			Alerts:   alertEngine.Alerts(),
+			Silences: alertEngine.Silences(),
+		})
+	})
+
+	http.HandleFunc("/api/alerts/silences", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if !auth.Allow(w, r, auth.PermAlertsRead) {
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(alertEngine.Silences())
+		case http.MethodPost:
+			if !auth.Allow(w, r, auth.PermAlertsSilence) {
+				return
+			}
+			createSilence(w, r)
+		case http.MethodDelete:
+			if !auth.Allow(w, r, auth.PermAlertsSilence) {
+				return
+			}
+			deleteSilence(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func createSilence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	badRequest := func(err error) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+
+	var req SilenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if req.Rule == "" {
+		badRequest(errors.New("rule is required"))
+		return
+	}
+
+	until := req.Until
+	if req.Duration != "" {
+		d, err := config.ParseDuration(req.Duration)
+		if err != nil || d == 0 {
+			badRequest(fmt.Errorf("invalid duration %q", req.Duration))
+			return
+		}
+		until = time.Now().Add(d)
+	}
+	if until.IsZero() {
+		badRequest(errors.New("duration or until is required"))
+		return
+	}
+
+	actor := "unknown"
+	if id, ok := auth.FromContext(r.Context()); ok {
+		actor = id.Username
+	}
+
+	silence, err := alertEngine.AddSilence(req.Rule, until, req.Comment, actor)
+	recordAudit(r, audit.ActionAlertSilence, req.Rule, err, map[string]string{
+		"until":   until.Format(time.RFC3339),
+		"comment": req.Comment,
+	})
+	if err != nil {
+		badRequest(err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(silence)
+}
+
+func deleteSilence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "id is required"})
+		return
+	}
+
+	err := alertEngine.RemoveSilence(id)
+	recordAudit(r, audit.ActionAlertUnsilence, id, err, nil)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
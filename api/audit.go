@@ -0,0 +1,133 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/audit"
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+var auditLog *audit.Logger
+
+// InitAudit открывает журнал аудита. Если не открылся файл по умолчанию (например, сервер
+// запущен не от root), дашборд работает без аудита; явно заданный audit.file обязателен
+func InitAudit(cfg *config.Config) error {
+	l, err := audit.NewLogger(cfg)
+	if err != nil && cfg.Audit.File == config.Default().Audit.File {
+		log.Printf("⚠️  Audit log disabled: %v (set audit.file to a writable path)", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	auditLog = l
+	if l != nil {
+		log.Printf("📜 Audit log: %s", cfg.Audit.File)
+	}
+	return nil
+}
+
+// recordAudit пишет событие; ошибка действия попадает в запись как failure
+func recordAudit(r *http.Request, action, target string, actionErr error, details map[string]string) {
+	e := audit.FromRequest(r, action, target)
+	e.Details = details
+	if actionErr != nil {
+		e.Result = audit.ResultFailure
+		e.Error = actionErr.Error()
+	}
+	if err := auditLog.Record(e); err != nil {
+		log.Printf("⚠️  Audit log error: %v", err)
+	}
+}
+
+// fileHash возвращает sha256 файла или "" если файла нет
+func fileHash(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetupAuditRoutes — GET /api/audit?from=&to=&actor=&action=&page=&per_page=
+func SetupAuditRoutes() {
+	http.HandleFunc("/api/audit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !auth.Allow(w, r, auth.PermAuditRead) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		filter := audit.Filter{
+			Actor:  query.Get("actor"),
+			Action: query.Get("action"),
+		}
+
+		for name, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+			if v := query.Get(name); v != "" {
+				t, err := time.Parse(time.RFC3339, v)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]string{"error": "Invalid " + name + ", expected RFC3339"})
+					return
+				}
+				*dst = t
+			}
+		}
+
+		page := 1
+		if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+			page = p
+		}
+		perPage := 50
+		if n, err := strconv.Atoi(query.Get("per_page")); err == nil && n > 0 {
+			perPage = n
+		}
+		if perPage > 500 {
+			perPage = 500
+		}
+		// Смещение не должно переполнить int
+		if page-1 > math.MaxInt32/perPage {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid page, too large"})
+			return
+		}
+		filter.Offset = (page - 1) * perPage
+		filter.Limit = perPage
+
+		events, total, err := auditLog.Query(filter)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"events":   events,
+			"total":    total,
+			"page":     page,
+			"per_page": perPage,
+		})
+	})
+}
@@ -5,7 +5,11 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/audit"
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
 )
 
 // FileInfo represents file or directory information
@@ -58,6 +62,10 @@ func sanitizePath(baseDir, path string) (string, error) {
 // ListFiles returns a list of files in the specified directory
 func ListFiles(baseDir string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Allow(w, r, auth.PermFilesRead) {
+			return
+		}
+
 		path := r.URL.Query().Get("path")
 
 		sanitized, err := sanitizePath(baseDir, path)
@@ -114,6 +122,10 @@ func ListFiles(baseDir string) http.HandlerFunc {
 // ReadFile reads the content of a file
 func ReadFile(baseDir string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Allow(w, r, auth.PermFilesRead) {
+			return
+		}
+
 		path := r.URL.Query().Get("path")
 		if path == "" {
 			http.Error(w, "Path is required", http.StatusBadRequest)
@@ -156,6 +168,10 @@ func WriteFile(baseDir string) http.HandlerFunc {
 			return
 		}
 
+		if !auth.Allow(w, r, auth.PermFilesWrite) {
+			return
+		}
+
 		path := r.URL.Query().Get("path")
 		if path == "" {
 			http.Error(w, "Path is required", http.StatusBadRequest)
@@ -174,18 +190,26 @@ func WriteFile(baseDir string) http.HandlerFunc {
 			return
 		}
 
+		details := map[string]string{"before_sha256": fileHash(sanitized)}
+
 		// Ensure parent directory exists
 		dir := filepath.Dir(sanitized)
 		if err := os.MkdirAll(dir, 0755); err != nil {
+			recordAudit(r, audit.ActionFileWrite, path, err, details)
 			http.Error(w, "Failed to create directory", http.StatusInternalServerError)
 			return
 		}
 
 		if err := os.WriteFile(sanitized, []byte(req.Content), 0644); err != nil {
+			recordAudit(r, audit.ActionFileWrite, path, err, details)
 			http.Error(w, "Failed to write file", http.StatusInternalServerError)
 			return
 		}
 
+		details["after_sha256"] = fileHash(sanitized)
+		details["size"] = strconv.Itoa(len(req.Content))
+		recordAudit(r, audit.ActionFileWrite, path, nil, details)
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
 			"status": "success",
@@ -197,6 +221,10 @@ func WriteFile(baseDir string) http.HandlerFunc {
 // DeleteFile deletes a file or directory
 func DeleteFile(baseDir string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Allow(w, r, auth.PermFilesWrite) {
+			return
+		}
+
 		path := r.URL.Query().Get("path")
 		if path == "" {
 			http.Error(w, "Path is required", http.StatusBadRequest)
@@ -216,15 +244,21 @@ func DeleteFile(baseDir string) http.HandlerFunc {
 		}
 
 		if info.IsDir() {
+			details := map[string]string{"type": "directory"}
 			if err := os.RemoveAll(sanitized); err != nil {
+				recordAudit(r, audit.ActionFileDelete, path, err, details)
 				http.Error(w, "Failed to delete directory", http.StatusInternalServerError)
 				return
 			}
+			recordAudit(r, audit.ActionFileDelete, path, nil, details)
 		} else {
+			details := map[string]string{"type": "file", "before_sha256": fileHash(sanitized)}
 			if err := os.Remove(sanitized); err != nil {
+				recordAudit(r, audit.ActionFileDelete, path, err, details)
 				http.Error(w, "Failed to delete file", http.StatusInternalServerError)
 				return
 			}
+			recordAudit(r, audit.ActionFileDelete, path, nil, details)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -243,6 +277,10 @@ func CreateDirectory(baseDir string) http.HandlerFunc {
 			return
 		}
 
+		if !auth.Allow(w, r, auth.PermFilesWrite) {
+			return
+		}
+
 		path := r.URL.Query().Get("path")
 		if path == "" {
 			http.Error(w, "Path is required", http.StatusBadRequest)
@@ -256,9 +294,11 @@ func CreateDirectory(baseDir string) http.HandlerFunc {
 		}
 
 		if err := os.MkdirAll(sanitized, 0755); err != nil {
+			recordAudit(r, audit.ActionDirCreate, path, err, nil)
 			http.Error(w, "Failed to create directory", http.StatusInternalServerError)
 			return
 		}
+		recordAudit(r, audit.ActionDirCreate, path, nil, nil)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
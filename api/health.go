@@ -10,6 +10,8 @@ import (
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/host"
 	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
 	"github.com/waplay/picoclaw-dashboard/websocket"
 )
 
@@ -119,9 +121,21 @@ func GetHealth() (HealthResponse, error) {
 	}, nil
 }
 
-func SetupRoutes(hub *websocket.Hub) {
+// topicPermissions — право, нужное для подписки на топик WebSocket
+var topicPermissions = map[string]auth.Permission{
+	websocket.TopicMetrics: auth.PermHealthRead,
+	websocket.TopicService: auth.PermServiceRead,
+	websocket.TopicAlerts:  auth.PermAlertsRead,
+	websocket.TopicLogs:    auth.PermLogsRead,
+}
+
+func SetupRoutes(cfg *config.Config, hub *websocket.Hub) {
 	// Health endpoint
 	http.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Allow(w, r, auth.PermHealthRead) {
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 
 		health, err := GetHealth()
@@ -131,8 +145,8 @@ func SetupRoutes(hub *websocket.Hub) {
 			return
 		}
 
-		// Broadcast to WebSocket clients
-		hub.Broadcast(health)
+		// Рассылаем подписчикам топика metrics
+		hub.Publish(websocket.TopicMetrics, health)
 
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(health)
@@ -140,11 +154,18 @@ func SetupRoutes(hub *websocket.Hub) {
 
 	// WebSocket endpoint
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		websocket.HandleWebSocket(hub, w, r)
+		if !auth.Allow(w, r, auth.PermHealthRead) {
+			return
+		}
+		id, _ := auth.FromContext(r.Context())
+		websocket.HandleWebSocket(hub, w, r, func(topic string) bool {
+			perm, ok := topicPermissions[topic]
+			return ok && id.Role.Can(perm)
+		})
 	})
 
 	// File API endpoints
-	baseDir := "." // Default to working directory
+	baseDir := cfg.Files.BaseDir
 	http.HandleFunc("/api/files", ListFiles(baseDir))
 	http.HandleFunc("/api/file", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+	"github.com/waplay/picoclaw-dashboard/pkg/history"
+)
+
+// maxHistoryPoints — ограничение на размер ответа; шаг увеличивается автоматически
+const maxHistoryPoints = 2000
+
+var healthHistory *history.Store
+
+// HistoryResponse — ответ /api/health/history
+type HistoryResponse struct {
+	From   time.Time       `json:"from"`
+	To     time.Time       `json:"to"`
+	Step   string          `json:"step"`
+	Source string          `json:"source"` // raw, 1m, 5m, 1h
+	Points []history.Point `json:"points"`
+}
+
+// InitHistory создаёт хранилище истории метрик и запускает периодическое сохранение на диск
+func InitHistory(cfg *config.Config) error {
+	store, err := history.NewStore(cfg)
+	if err != nil {
+		return err
+	}
+	healthHistory = store
+
+	if cfg.History.PersistFile != "" {
+		go func() {
+			ticker := time.NewTicker(cfg.History.PersistInterval.Std())
+			defer ticker.Stop()
+			for range ticker.C {
+				if err := store.Save(); err != nil {
+					log.Printf("⚠️  Error saving metrics history: %v", err)
+				}
+			}
+		}()
+		log.Printf("📈 Metrics history persisted to %s", cfg.History.PersistFile)
+	}
+
+	return nil
+}
+
+// RecordHealth добавляет замер в историю
+func RecordHealth(h HealthResponse) {
+	if healthHistory == nil {
+		return
+	}
+	healthHistory.Add(history.Point{
+		Time:       h.CPU.Timestamp,
+		CPU:        h.CPU.Usage,
+		Memory:     h.Memory.UsedPercent,
+		MemoryUsed: float64(h.Memory.Used),
+		Disk:       h.Disk.UsedPercent,
+		DiskUsed:   float64(h.Disk.Used),
+	})
+}
+
+// SetupHistoryRoutes — GET /api/health/history?from=&to=&step=
+func SetupHistoryRoutes() {
+	http.HandleFunc("/api/health/history", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !auth.Allow(w, r, auth.PermHealthRead) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		badRequest := func(err error) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		}
+
+		query := r.URL.Query()
+		now := time.Now()
+
+		// По умолчанию — последний час
+		from, to := now.Add(-time.Hour), now
+		if v := query.Get("from"); v != "" {
+			t, err := config.ParseTime(v, now)
+			if err != nil {
+				badRequest(err)
+				return
+			}
+			from = t
+		}
+		if v := query.Get("to"); v != "" {
+			t, err := config.ParseTime(v, now)
+			if err != nil {
+				badRequest(err)
+				return
+			}
+			to = t
+		}
+		if !from.Before(to) {
+			badRequest(fmt.Errorf("from must be before to"))
+			return
+		}
+
+		step := healthHistory.Resolution()
+		if v := query.Get("step"); v != "" {
+			d, err := config.ParseDuration(v)
+			if err != nil || d == 0 {
+				badRequest(fmt.Errorf("invalid step %q", v))
+				return
+			}
+			step = d
+		}
+		if minStep := to.Sub(from) / maxHistoryPoints; step < minStep {
+			step = minStep.Round(time.Second)
+		}
+
+		points, source := healthHistory.Query(from, to, step)
+		if points == nil {
+			points = []history.Point{}
+		}
+
+		json.NewEncoder(w).Encode(HistoryResponse{
+			From:   from,
+			To:     to,
+			Step:   step.String(),
+			Source: source,
+			Points: points,
+		})
+	})
+}
@@ -1,10 +1,16 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
 	"github.com/waplay/picoclaw-dashboard/pkg/logs"
+	"github.com/waplay/picoclaw-dashboard/websocket"
 )
 
 var (
@@ -12,11 +18,15 @@ var (
 	logHandler *logs.Handler
 )
 
-// InitLogsService инициализирует сервис логов
-func InitLogsService(unit string) {
-	logService = logs.NewService(unit)
+// InitLogsService инициализирует сервис логов и топик logs в WebSocket hub
+func InitLogsService(cfg *config.Config, hub *websocket.Hub) {
+	logService = logs.NewService(cfg)
 	logHandler = logs.NewHandler(logService)
-	log.Printf("📝 Logs service initialized for unit: %s", unit)
+
+	topic := &logTopic{hub: hub, followers: make(map[string]*logFollower)}
+	hub.Handle(websocket.TopicLogs, topic.subscribe)
+
+	log.Printf("📝 Logs service initialized for units: %s", strings.Join(cfg.Logs.AllowedUnits(), ", "))
 }
 
 // SetupLogRoutes регистрирует роуты для API логов
@@ -28,3 +38,137 @@ func SetupLogRoutes() {
 	logHandler.RegisterRoutes(http.DefaultServeMux)
 	log.Println("📝 Log routes registered")
 }
+
+// LogTopicParams — params подписки на топик logs
+type LogTopicParams struct {
+	Unit     string            `json:"unit"`  // по умолчанию logs.unit
+	Units    []string          `json:"units"` // несколько юнитов из logs.units, вместе с Unit
+	Level    string            `json:"level"`
+	MinLevel string            `json:"min_level"`
+	Search   string            `json:"search"`
+	Regex    string            `json:"regex"`
+	Query    string            `json:"query"`  // язык запросов, как q в /api/logs
+	Fields   map[string]string `json:"fields"` // поле → значение, как field.<имя> в /api/logs
+}
+
+// logTopic держит одну подписку на общий follower юнита (logs.Service.Subscribe), пока
+// на юнит подписан хотя бы один клиент. Записи публикуются в hub один раз,
+// фильтры каждого клиента применяет его подписка
+type logTopic struct {
+	hub *websocket.Hub
+
+	mu        sync.Mutex
+	followers map[string]*logFollower
+}
+
+type logFollower struct {
+	refs int
+	sub  *logs.Subscription
+}
+
+func (t *logTopic) subscribe(params json.RawMessage) (websocket.Subscription, error) {
+	var p LogTopicParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+	units := make(map[string]bool)
+	for _, unit := range append(p.Units, p.Unit) {
+		if unit == "" {
+			continue
+		}
+		if !logService.Allowed(unit) {
+			return nil, fmt.Errorf("unit %q is not allowed", unit)
+		}
+		units[unit] = true
+	}
+	if len(units) == 0 {
+		units[logService.Unit()] = true
+	}
+
+	matcher, err := logs.NewMatcher(logs.LogFilter{
+		Level:    p.Level,
+		MinLevel: p.MinLevel,
+		Search:   p.Search,
+		Regex:    p.Regex,
+		Query:    p.Query,
+		Fields:   p.Fields,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for unit := range units {
+		t.acquire(unit)
+	}
+	return &logSubscription{topic: t, units: units, matcher: matcher}, nil
+}
+
+// acquire подписывается на follower юнита для первого клиента
+func (t *logTopic) acquire(unit string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, ok := t.followers[unit]
+	if !ok {
+		f = &logFollower{sub: logService.Subscribe(unit, false)}
+		t.followers[unit] = f
+		go t.forward(unit, f.sub)
+	}
+	f.refs++
+}
+
+// release отписывается от follower'а, когда ушёл последний клиент
+func (t *logTopic) release(unit string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, ok := t.followers[unit]
+	if !ok {
+		return
+	}
+	f.refs--
+	if f.refs <= 0 {
+		f.sub.Close()
+		delete(t.followers, unit)
+	}
+}
+
+// forward публикует записи (logs.LogEntry) в hub. Unit записи — юнит follower'а: сообщения
+// systemd о юните приходят с _SYSTEMD_UNIT=init.scope. Если подписку отключили за отставание,
+// подписывается заново
+func (t *logTopic) forward(unit string, sub *logs.Subscription) {
+	for entry := range sub.Entries {
+		entry.Unit = unit
+		t.hub.Publish(websocket.TopicLogs, entry)
+	}
+	if !sub.Dropped() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if f, ok := t.followers[unit]; ok && f.sub == sub {
+		f.sub = logService.Subscribe(unit, false)
+		go t.forward(unit, f.sub)
+	}
+}
+
+// logSubscription — подписка клиента на логи юнитов со своими фильтрами
+type logSubscription struct {
+	topic   *logTopic
+	units   map[string]bool
+	matcher *logs.Matcher
+}
+
+func (s *logSubscription) Match(payload interface{}) bool {
+	entry, ok := payload.(logs.LogEntry)
+	return ok && s.units[entry.Unit] && s.matcher.Match(entry)
+}
+
+func (s *logSubscription) Close() {
+	for unit := range s.units {
+		s.topic.release(unit)
+	}
+}
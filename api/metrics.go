@@ -0,0 +1,157 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"runtime"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
+	"github.com/waplay/picoclaw-dashboard/pkg/metrics"
+	"github.com/waplay/picoclaw-dashboard/websocket"
+)
+
+var httpRequestDuration = metrics.NewHistogramVec(
+	"picoclaw_dashboard_http_request_duration_seconds",
+	"HTTP request latency by route pattern, method and status code.",
+	metrics.DefaultBuckets,
+	"route", "method", "code",
+)
+
+// InstrumentHTTP оборачивает обработчик сервера замером задержки по роутам
+func InstrumentHTTP(next http.Handler) http.Handler {
+	return metrics.InstrumentHandler(http.DefaultServeMux, httpRequestDuration, next)
+}
+
+// collectHost собирает метрики хоста и сервиса в момент scrape
+func collectHost(e *metrics.Encoder) {
+	health, err := GetHealth()
+	if err != nil {
+		log.Printf("⚠️  /metrics: error getting health: %v", err)
+	} else {
+		e.Gauge("picoclaw_host_cpu_usage_percent", "CPU usage in percent.", health.CPU.Usage)
+		e.Gauge("picoclaw_host_cpu_cores", "Number of logical CPU cores.", float64(health.CPU.Cores))
+
+		e.Gauge("picoclaw_host_memory_total_bytes", "Total memory in bytes.", float64(health.Memory.Total))
+		e.Gauge("picoclaw_host_memory_used_bytes", "Used memory in bytes.", float64(health.Memory.Used))
+		e.Gauge("picoclaw_host_memory_available_bytes", "Available memory in bytes.", float64(health.Memory.Available))
+		e.Gauge("picoclaw_host_memory_used_percent", "Used memory in percent.", health.Memory.UsedPercent)
+
+		disk := metrics.Labels{"path": health.Disk.Path}
+		for _, m := range []struct {
+			name, help string
+			value      float64
+		}{
+			{"picoclaw_host_disk_total_bytes", "Disk size in bytes.", float64(health.Disk.Total)},
+			{"picoclaw_host_disk_used_bytes", "Used disk space in bytes.", float64(health.Disk.Used)},
+			{"picoclaw_host_disk_free_bytes", "Free disk space in bytes.", float64(health.Disk.Free)},
+			{"picoclaw_host_disk_used_percent", "Used disk space in percent.", health.Disk.UsedPercent},
+		} {
+			e.Family(m.name, m.help, metrics.TypeGauge)
+			e.Sample(m.name, disk, m.value)
+		}
+
+		e.Gauge("picoclaw_host_uptime_seconds", "Host uptime in seconds.", float64(health.Uptime.Uptime))
+		e.Gauge("picoclaw_host_boot_time_seconds", "Host boot time as unix timestamp.", float64(health.Uptime.BootTime.Unix()))
+	}
+
+	type serviceGauge struct {
+		name, help string
+		value      func(ServiceResponse) float64
+	}
+	gauges := []serviceGauge{
+		{"picoclaw_service_active", "Whether the unit is active (1) or not (0).", func(s ServiceResponse) float64 { return boolToFloat(s.Active) }},
+		{"picoclaw_service_running", "Whether the unit is running (1) or not (0).", func(s ServiceResponse) float64 { return boolToFloat(s.Running) }},
+		{"picoclaw_service_loaded", "Whether the unit is loaded (1) or not (0).", func(s ServiceResponse) float64 { return boolToFloat(s.Loaded) }},
+		{"picoclaw_service_enabled", "Whether the unit is enabled (1) or not (0).", func(s ServiceResponse) float64 { return boolToFloat(s.Enabled) }},
+	}
+
+	var statuses []ServiceResponse
+	for _, status := range GetAllServiceStatuses() {
+		if status.Error != "" {
+			log.Printf("⚠️  /metrics: error getting status of %s: %s", status.Unit, status.Error)
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	if len(statuses) == 0 {
+		return
+	}
+
+	// Семейство пишется один раз, сэмплы — по каждому юниту
+	for _, g := range gauges {
+		e.Family(g.name, g.help, metrics.TypeGauge)
+		for _, status := range statuses {
+			e.Sample(g.name, metrics.Labels{"unit": status.Unit}, g.value(status))
+		}
+	}
+	e.Family("picoclaw_service_active_since_seconds", "Time the unit entered the active state as unix timestamp.", metrics.TypeGauge)
+	for _, status := range statuses {
+		if !status.ActiveSince.IsZero() {
+			e.Sample("picoclaw_service_active_since_seconds", metrics.Labels{"unit": status.Unit}, float64(status.ActiveSince.Unix()))
+		}
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// SetupMetricsRoutes — GET /metrics в формате Prometheus
+func SetupMetricsRoutes(hub *websocket.Hub) {
+	registry := metrics.NewRegistry()
+	registry.Register(
+		metrics.CollectorFunc(collectHost),
+		metrics.CollectorFunc(func(e *metrics.Encoder) {
+			e.Family("picoclaw_dashboard_build_info", "Dashboard build information.", metrics.TypeGauge)
+			e.Sample("picoclaw_dashboard_build_info", metrics.Labels{"go_version": runtime.Version()}, 1)
+		}),
+		metrics.NewGaugeFunc("picoclaw_dashboard_websocket_clients",
+			"Connected WebSocket clients.",
+			func() float64 { return float64(hub.ClientCount()) }),
+		metrics.NewCounterFunc("picoclaw_dashboard_websocket_dropped_broadcasts_total",
+			"Published messages dropped because the hub channel was full.",
+			func() float64 { return float64(hub.DroppedBroadcasts()) }),
+		metrics.NewCounterFunc("picoclaw_dashboard_websocket_dropped_clients_total",
+			"WebSocket clients disconnected because their send buffer was full.",
+			func() float64 { return float64(hub.DroppedClients()) }),
+		metrics.NewGaugeFunc("picoclaw_dashboard_log_stream_subscribers",
+			"Open log stream (SSE) connections.",
+			func() float64 {
+				if logHandler == nil {
+					return 0
+				}
+				return float64(logHandler.Subscribers())
+			}),
+		metrics.NewGaugeFunc("picoclaw_dashboard_log_followers",
+			"Running journal followers (one journalctl -f per unit).",
+			func() float64 {
+				if logService == nil {
+					return 0
+				}
+				return float64(logService.Followers())
+			}),
+		metrics.NewCounterFunc("picoclaw_dashboard_log_dropped_subscribers_total",
+			"Log stream subscribers disconnected because their queue was full.",
+			func() float64 {
+				if logService == nil {
+					return 0
+				}
+				return float64(logService.DroppedSubscribers())
+			}),
+		httpRequestDuration,
+	)
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !auth.Allow(w, r, auth.PermMetricsRead) {
+			return
+		}
+		registry.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/alerts"
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+	"github.com/waplay/picoclaw-dashboard/pkg/logs"
+	"github.com/waplay/picoclaw-dashboard/pkg/notify"
+)
+
+var (
+	notifier           *notify.Dispatcher
+	notifyServiceState bool
+)
+
+// InitNotifications создаёт каналы уведомлений и подписывает их на алерты и логи.
+// Вызывается после InitAlerts и InitLogsService
+func InitNotifications(cfg *config.Config) error {
+	d, err := notify.NewDispatcher(cfg)
+	if err != nil {
+		return err
+	}
+	if d.Len() == 0 {
+		return nil
+	}
+	notifier = d
+	notifyServiceState = cfg.Notify.ServiceTransitions
+
+	if alertEngine != nil {
+		alertEngine.Subscribe(notifyAlert)
+	}
+	if cfg.Notify.LogErrors && logService != nil && d.Wants(notify.KindLog) {
+		for _, unit := range cfg.Logs.AllowedUnits() {
+			go followLogErrors(unit)
+		}
+	}
+
+	log.Printf("📣 Notifications: %d channel(s)", d.Len())
+	return nil
+}
+
+// notifyAlert отправляет срабатывание и снятие алерта. pending и заглушенные алерты не отправляются
+func notifyAlert(e alerts.Event) {
+	if e.Alert.Silenced {
+		return
+	}
+
+	rule := e.Alert.Rule
+	msg := notify.Message{
+		Kind:     notify.KindAlert,
+		Severity: rule.Severity,
+		Time:     e.Time,
+		Fields: map[string]string{
+			"rule":  rule.Name,
+			"expr":  rule.Expr,
+			"value": fmt.Sprintf("%g", e.Alert.Value),
+		},
+	}
+	switch e.Alert.State {
+	case alerts.StateFiring:
+		msg.Title = "Alert firing: " + rule.Name
+		msg.Text = rule.Description
+	case alerts.StateResolved:
+		msg.Severity = "info"
+		msg.Title = "Alert resolved: " + rule.Name
+		msg.Text = rule.Description
+	default:
+		return
+	}
+	if msg.Text == "" {
+		msg.Text = fmt.Sprintf("%s (current value %g)", rule.Expr, e.Alert.Value)
+	}
+	notifier.Notify(msg)
+}
+
+// ObserveService отправляет уведомление о смене статуса сервиса (Running, Stopped, Failed, ...)
+func ObserveService(prev, cur ServiceResponse) {
+	if notifier == nil || !notifyServiceState || prev.Status == cur.Status {
+		return
+	}
+
+	severity := "info"
+	if cur.Status == "Stopped" || cur.Status == "Failed" {
+		severity = "critical"
+	}
+	fields := map[string]string{
+		"unit":  cur.Unit,
+		"state": cur.ActiveState + "/" + cur.SubState,
+	}
+	if cur.Status == "Failed" {
+		fields["result"] = cur.Result
+		fields["exit_code"] = fmt.Sprint(cur.ExitCode)
+	}
+
+	notifier.Notify(notify.Message{
+		Kind:     notify.KindService,
+		Severity: severity,
+		Title:    fmt.Sprintf("%s: %s → %s", cur.Unit, prev.Status, cur.Status),
+		Text:     fmt.Sprintf("Service %s changed state from %s to %s", cur.Unit, prev.Status, cur.Status),
+		Time:     cur.Timestamp,
+		Fields:   fields,
+	})
+}
+
+// followLogErrors отправляет новые записи уровня ERROR и выше. Подписка на общий follower юнита;
+// если её отключили за отставание, подписывается заново
+func followLogErrors(unit string) {
+	for {
+		sub := logService.Subscribe(unit, false)
+		for entry := range sub.Entries {
+			if msg, ok := logErrorMessage(unit, entry); ok {
+				notifier.Notify(msg)
+			}
+		}
+		log.Printf("⚠️  Notify: log subscription for %s dropped, resubscribing", unit)
+	}
+}
+
+// logErrorMessage — уведомление о записи лога: ERROR уходит как warning, FATAL — как critical.
+// Записи ниже ERROR не отправляются
+func logErrorMessage(unit string, entry logs.LogEntry) (notify.Message, bool) {
+	level := logs.EntrySeverity(entry)
+	msg := notify.Message{
+		Kind:   notify.KindLog,
+		Text:   entry.Message,
+		Time:   entry.Timestamp,
+		Fields: map[string]string{"unit": unit, "level": level.String()},
+	}
+	switch {
+	case level >= logs.SeverityFatal:
+		msg.Severity = "critical"
+		msg.Title = unit + ": fatal error in logs"
+	case level >= logs.SeverityError:
+		msg.Severity = "warning"
+		msg.Title = unit + ": error in logs"
+	default:
+		return notify.Message{}, false
+	}
+	return msg, true
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/logs"
+	"github.com/waplay/picoclaw-dashboard/pkg/notify"
+)
+
+func TestLogErrorMessage(t *testing.T) {
+	now := time.Date(2026, 2, 21, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		entry    logs.LogEntry
+		severity string // пусто — запись не отправляется
+		title    string
+	}{
+		{logs.LogEntry{Level: "INFO", Priority: 6}, "", ""},
+		{logs.LogEntry{Level: "WARN", Priority: 4}, "", ""},
+		{logs.LogEntry{Level: "ERROR", Priority: 3}, "warning", "worker: error in logs"},
+		{logs.LogEntry{Level: "FATAL", Priority: 2}, "critical", "worker: fatal error in logs"},
+		// Уровень не распознан — решает PRIORITY
+		{logs.LogEntry{Priority: 3}, "warning", "worker: error in logs"},
+		{logs.LogEntry{Priority: 0}, "critical", "worker: fatal error in logs"},
+	}
+	for _, tt := range tests {
+		tt.entry.Message = "boom"
+		tt.entry.Timestamp = now
+		msg, ok := logErrorMessage("worker", tt.entry)
+		if ok != (tt.severity != "") {
+			t.Errorf("%+v: sent = %v", tt.entry, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if msg.Kind != notify.KindLog || msg.Severity != tt.severity || msg.Title != tt.title {
+			t.Errorf("%+v: kind %q, severity %q, title %q; want %q, %q", tt.entry, msg.Kind, msg.Severity, msg.Title, tt.severity, tt.title)
+		}
+		if msg.Text != "boom" || !msg.Time.Equal(now) || msg.Fields["unit"] != "worker" || msg.Fields["level"] == "" {
+			t.Errorf("%+v: message = %+v", tt.entry, msg)
+		}
+	}
+}
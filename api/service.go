@@ -1,93 +1,187 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/audit"
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+	"github.com/waplay/picoclaw-dashboard/pkg/systemd"
+	"github.com/waplay/picoclaw-dashboard/websocket"
 )
 
 // ServiceResponse — статус сервиса
 type ServiceResponse struct {
+	Unit        string    `json:"unit"`
 	Active      bool      `json:"active"`
 	Running     bool      `json:"running"`
 	Loaded      bool      `json:"loaded"`
 	Enabled     bool      `json:"enabled"`
 	Status      string    `json:"status"`
+	ActiveState string    `json:"active_state"` // состояние systemd как есть
+	SubState    string    `json:"sub_state"`
+	Result      string    `json:"result,omitempty"` // success, exit-code, signal, ...
+	ExitCode    int       `json:"exit_code"`        // код выхода главного процесса
 	ActiveSince time.Time `json:"active_since"`
 	Timestamp   time.Time `json:"timestamp"`
+	Error       string    `json:"error,omitempty"` // только в списке /api/services
 }
 
-// ServiceUnit — имя systemd сервиса (picoclaw)
-const ServiceUnit = "picoclaw"
+// serviceTimeout — ограничение на запрос статуса и выполнение действия
+const serviceTimeout = 30 * time.Second
 
 var (
-	serviceStatusCache ServiceResponse
-	serviceCacheTime   time.Time
+	serviceManager  systemd.Manager
+	serviceUnit     string        // основной сервис из конфигурации
+	serviceUnits    []string      // все сервисы, которыми можно управлять
+	serviceCacheTTL time.Duration // время жизни кэша статуса
+
+	serviceCacheMu sync.Mutex
+	serviceCache   = map[string]serviceCacheEntry{}
 )
 
-// GetServiceStatus — получить статус сервиса picoclaw
+type serviceCacheEntry struct {
+	status ServiceResponse
+	time   time.Time
+}
+
+// allowedUnit проверяет, что сервис есть в списке service.unit/service.units
+func allowedUnit(unit string) bool {
+	for _, u := range serviceUnits {
+		if u == unit {
+			return true
+		}
+	}
+	return false
+}
+
+// GetServiceStatus — получить статус основного сервиса
 func GetServiceStatus() (ServiceResponse, error) {
-	// Проверяем состояние кэша (обновляем не чаще чем раз в 5 секунд)
-	if time.Since(serviceCacheTime) < 5*time.Second {
-		return serviceStatusCache, nil
+	return GetUnitStatus(serviceUnit)
+}
+
+// GetAllServiceStatuses — статусы всех разрешённых сервисов; ошибка попадает в поле Error
+func GetAllServiceStatuses() []ServiceResponse {
+	result := make([]ServiceResponse, 0, len(serviceUnits))
+	for _, unit := range serviceUnits {
+		status, err := GetUnitStatus(unit)
+		if err != nil {
+			status = ServiceResponse{Unit: unit, Status: "Unknown", Timestamp: time.Now(), Error: err.Error()}
+		}
+		result = append(result, status)
 	}
+	return result
+}
 
-	// Используем systemctl show для получения подробной информации
-	cmd := exec.Command("systemctl", "show", "--property=ActiveState,SubState,LoadState,UnitFileState,ActiveEnterTimestamp", ServiceUnit)
-	output, err := cmd.Output()
-	if err != nil {
-		return ServiceResponse{}, fmt.Errorf("failed to get service status: %w", err)
+// GetUnitStatus — получить статус сервиса из списка разрешённых
+func GetUnitStatus(unit string) (ServiceResponse, error) {
+	// Проверяем состояние кэша (обновляем не чаще чем раз в serviceCacheTTL)
+	serviceCacheMu.Lock()
+	cached, ok := serviceCache[unit]
+	serviceCacheMu.Unlock()
+	if ok && time.Since(cached.time) < serviceCacheTTL {
+		return cached.status, nil
 	}
 
-	response := ServiceResponse{
-		Timestamp: time.Now(),
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+
+	unitStatus, err := serviceManager.Status(ctx, unit)
+	if err != nil {
+		return ServiceResponse{}, err
 	}
+	response := newServiceResponse(unitStatus)
 
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		key, value := parts[0], parts[1]
-
-		switch key {
-		case "ActiveState":
-			response.Active = value == "active"
-		case "SubState":
-			response.Running = value == "running"
-		case "LoadState":
-			response.Loaded = value == "loaded"
-		case "UnitFileState":
-			response.Enabled = value == "enabled"
-		case "ActiveEnterTimestamp":
-			if t, err := time.Parse("Mon 2006-01-02 15:04:05 MST", value); err == nil {
-				response.ActiveSince = t
-			}
-		}
+	// Обновляем кэш
+	serviceCacheMu.Lock()
+	serviceCache[unit] = serviceCacheEntry{status: response, time: time.Now()}
+	serviceCacheMu.Unlock()
+
+	return response, nil
+}
+
+// newServiceResponse переводит состояние юнита в ответ API
+func newServiceResponse(u systemd.UnitStatus) ServiceResponse {
+	response := ServiceResponse{
+		Unit:        u.Unit,
+		Active:      u.ActiveState == "active",
+		Running:     u.SubState == "running",
+		Loaded:      u.LoadState == "loaded",
+		Enabled:     u.UnitFileState == "enabled",
+		ActiveState: u.ActiveState,
+		SubState:    u.SubState,
+		Result:      u.Result,
+		ExitCode:    u.ExecMainStatus,
+		ActiveSince: u.ActiveEnterTimestamp,
+		Timestamp:   time.Now(),
 	}
 
 	// Определяем текстовый статус
-	if response.Active && response.Running {
+	switch {
+	case response.Active && response.Running:
 		response.Status = "Running"
-	} else if response.Active {
+	case response.Active:
 		response.Status = "Active"
-	} else {
+	case u.ActiveState == "failed":
+		response.Status = "Failed"
+	default:
 		response.Status = "Stopped"
 	}
 
-	// Обновляем кэш
-	serviceStatusCache = response
-	serviceCacheTime = time.Now()
+	return response
+}
 
-	return response, nil
+// ServiceEvent — переход юнита в другое состояние, рассылается в топик service
+type ServiceEvent struct {
+	Unit        string          `json:"unit"`
+	Previous    string          `json:"previous"` // ActiveState до перехода
+	State       string          `json:"state"`    // ActiveState после перехода
+	PreviousSub string          `json:"previous_sub_state"`
+	SubState    string          `json:"sub_state"`
+	Result      string          `json:"result,omitempty"`    // при failed: exit-code, signal, timeout, ...
+	ExitCode    *int            `json:"exit_code,omitempty"` // при failed
+	Time        time.Time       `json:"time"`
+	Status      ServiceResponse `json:"status"` // полный статус после перехода
+}
+
+// StartServiceWatcher следит за переходами разрешённых юнитов: обновляет кэш статуса,
+// рассылает ServiceEvent через hub и передаёт смену статуса в уведомления
+func StartServiceWatcher(cfg *config.Config, hub *websocket.Hub) {
+	watcher := systemd.NewWatcher(serviceManager, serviceUnits, cfg.Service.WatchInterval.Std())
+	watcher.Subscribe(func(c systemd.StateChange) {
+		status := newServiceResponse(c.Current)
+
+		serviceCacheMu.Lock()
+		serviceCache[c.Unit] = serviceCacheEntry{status: status, time: time.Now()}
+		serviceCacheMu.Unlock()
+
+		event := ServiceEvent{
+			Unit:        c.Unit,
+			Previous:    c.Previous.ActiveState,
+			State:       c.Current.ActiveState,
+			PreviousSub: c.Previous.SubState,
+			SubState:    c.Current.SubState,
+			Time:        c.Time,
+			Status:      status,
+		}
+		if c.Current.ActiveState == "failed" {
+			code := c.Current.ExecMainStatus
+			event.Result = c.Current.Result
+			event.ExitCode = &code
+		}
+
+		log.Printf("🔧 %s: %s/%s -> %s/%s", c.Unit, event.Previous, event.PreviousSub, event.State, event.SubState)
+		hub.Publish(websocket.TopicService, event)
+		ObserveService(newServiceResponse(c.Previous), status)
+	})
+	go watcher.Run(context.Background())
 }
 
 // ServiceAction — действие над сервисом
@@ -95,41 +189,82 @@ type ServiceAction struct {
 	Action string `json:"action"` // start, stop, restart
 }
 
-// ControlService — управление сервисом
+// serviceAuditActions — действия над сервисом для журнала аудита
+var serviceAuditActions = map[string]string{
+	"start":   audit.ActionServiceStart,
+	"stop":    audit.ActionServiceStop,
+	"restart": audit.ActionServiceRestart,
+}
+
+// ControlService — управление основным сервисом
 func ControlService(action string) error {
-	var cmd *exec.Cmd
-
-	switch action {
-	case "start":
-		cmd = exec.Command("sudo", "-n", "systemctl", "start", ServiceUnit)
-	case "stop":
-		cmd = exec.Command("sudo", "-n", "systemctl", "stop", ServiceUnit)
-	case "restart":
-		cmd = exec.Command("sudo", "-n", "systemctl", "restart", ServiceUnit)
-	default:
-		return fmt.Errorf("invalid action: %s", action)
+	return ControlUnit(serviceUnit, action)
+}
+
+// ControlUnit — управление сервисом из списка разрешённых
+func ControlUnit(unit, action string) error {
+	if !allowedUnit(unit) {
+		return fmt.Errorf("unit %s is not managed by the dashboard", unit)
 	}
 
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to %s service: %w\nOutput: %s", action, err, string(output))
+	ctx, cancel := context.WithTimeout(context.Background(), serviceTimeout)
+	defer cancel()
+
+	if err := serviceManager.Control(ctx, unit, action); err != nil {
+		return err
 	}
 
-	log.Printf("🔧 Service action '%s' executed successfully for %s", action, ServiceUnit)
+	log.Printf("🔧 Service action '%s' executed successfully for %s", action, unit)
 
 	// Сбрасываем кэш статуса после действия
-	serviceCacheTime = time.Time{}
+	serviceCacheMu.Lock()
+	delete(serviceCache, unit)
+	serviceCacheMu.Unlock()
 
 	return nil
 }
 
+// InitServices выбирает бэкенд systemd (D-Bus, systemctl или фейк) и список сервисов
+func InitServices(cfg *config.Config) error {
+	m, err := systemd.New(cfg)
+	if err != nil {
+		return err
+	}
+	SetServiceManager(m, cfg.Service.Unit, cfg.Service.AllowedUnits(), cfg.Service.CacheTTL.Std())
+
+	log.Printf("🔧 Service backend: %s (units: %s)", m.Name(), strings.Join(serviceUnits, ", "))
+	return nil
+}
+
+// SetServiceManager подставляет бэкенд systemd и список сервисов (например, systemd.Fake в тестах)
+// и сбрасывает кэш статуса
+func SetServiceManager(m systemd.Manager, unit string, units []string, cacheTTL time.Duration) {
+	serviceManager = m
+	serviceUnit = unit
+	serviceUnits = units
+	serviceCacheTTL = cacheTTL
+
+	serviceCacheMu.Lock()
+	serviceCache = map[string]serviceCacheEntry{}
+	serviceCacheMu.Unlock()
+}
+
 // SetupServiceRoutes — регистрирует роуты для управления сервисом
 func SetupServiceRoutes() {
+	registerServiceRoutes(http.DefaultServeMux)
+	log.Println("🔧 Service routes registered")
+}
+
+func registerServiceRoutes(mux *http.ServeMux) {
 	// GET /api/service — получить статус сервиса
-	http.HandleFunc("/api/service", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/service", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		if !auth.Allow(w, r, auth.PermServiceRead) {
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 
@@ -143,36 +278,97 @@ func SetupServiceRoutes() {
 		json.NewEncoder(w).Encode(status)
 	})
 
-	// POST /api/service/action — действие над сервисом
-	http.HandleFunc("/api/service/action", func(w http.ResponseWriter, r *http.Request) {
+	// POST /api/service/action — действие над основным сервисом
+	mux.HandleFunc("/api/service/action", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-
-		var req ServiceAction
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		if !auth.Allow(w, r, auth.PermServiceControl) {
 			return
 		}
+		handleServiceAction(w, r, serviceUnit)
+	})
 
-		if err := ControlService(req.Action); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	// GET /api/services — статусы всех разрешённых сервисов
+	mux.HandleFunc("/api/services", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !auth.Allow(w, r, auth.PermServiceRead) {
 			return
 		}
 
-		// Возвращаем обновлённый статус
-		status, err := GetServiceStatus()
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetAllServiceStatuses())
+	})
+
+	// GET /api/services/{unit} — статус сервиса, POST /api/services/{unit}/action — действие над ним
+	mux.HandleFunc("/api/services/", func(w http.ResponseWriter, r *http.Request) {
+		unit, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/services/"), "/")
+		if !allowedUnit(unit) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("unit %q is not managed by the dashboard", unit)})
 			return
 		}
 
-		json.NewEncoder(w).Encode(status)
+		switch {
+		case rest == "" && r.Method == http.MethodGet:
+			if !auth.Allow(w, r, auth.PermServiceRead) {
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			status, err := GetUnitStatus(unit)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(status)
+		case rest == "action" && r.Method == http.MethodPost:
+			if !auth.Allow(w, r, auth.PermServiceControl) {
+				return
+			}
+			handleServiceAction(w, r, unit)
+		case rest == "" || rest == "action":
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		default:
+			http.NotFound(w, r)
+		}
 	})
+}
 
-	log.Println("🔧 Service routes registered")
+// handleServiceAction выполняет действие из тела запроса и возвращает обновлённый статус
+func handleServiceAction(w http.ResponseWriter, r *http.Request, unit string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ServiceAction
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	err := ControlUnit(unit, req.Action)
+	if action, ok := serviceAuditActions[req.Action]; ok {
+		recordAudit(r, action, unit, err, nil)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Возвращаем обновлённый статус
+	status, err := GetUnitStatus(unit)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(status)
 }
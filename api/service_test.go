@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
+	"github.com/waplay/picoclaw-dashboard/pkg/systemd"
+)
+
+// newServiceTest подставляет systemd.Fake (разрешены picoclaw и worker, other — нет) и возвращает роутер
+func newServiceTest(t *testing.T) (*systemd.Fake, *http.ServeMux) {
+	t.Helper()
+	fake := systemd.NewFake("picoclaw.service", "worker.service", "other.service")
+	SetServiceManager(fake, "picoclaw.service", []string{"picoclaw.service", "worker.service"}, 0)
+	t.Cleanup(func() { SetServiceManager(nil, "", nil, 0) })
+
+	mux := http.NewServeMux()
+	registerServiceRoutes(mux)
+	return fake, mux
+}
+
+// serveAs выполняет запрос от пользователя с ролью role
+func serveAs(mux *http.ServeMux, role auth.Role, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r = r.WithContext(auth.WithIdentity(r.Context(), auth.Identity{Username: "test", Role: role}))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func decodeStatus(t *testing.T, w *httptest.ResponseRecorder) ServiceResponse {
+	t.Helper()
+	var status ServiceResponse
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	return status
+}
+
+func TestServiceStatus(t *testing.T) {
+	_, mux := newServiceTest(t)
+
+	w := serveAs(mux, auth.RoleViewer, http.MethodGet, "/api/service", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code = %d, body %s", w.Code, w.Body)
+	}
+	status := decodeStatus(t, w)
+	if status.Unit != "picoclaw.service" || status.Status != "Running" || !status.Active || !status.Enabled {
+		t.Errorf("status = %+v", status)
+	}
+
+	w = serveAs(mux, auth.RoleViewer, http.MethodGet, "/api/services/worker.service", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unit status code = %d, body %s", w.Code, w.Body)
+	}
+	if status := decodeStatus(t, w); status.Unit != "worker.service" {
+		t.Errorf("unit = %q, want worker.service", status.Unit)
+	}
+
+	w = serveAs(mux, auth.RoleViewer, http.MethodGet, "/api/services", "")
+	var list []ServiceResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list) != 2 || list[0].Unit != "picoclaw.service" || list[1].Unit != "worker.service" {
+		t.Errorf("list = %+v", list)
+	}
+}
+
+func TestServiceActions(t *testing.T) {
+	tests := []struct {
+		action string
+		before func(f *systemd.Fake)
+		status string
+	}{
+		{action: "stop", status: "Stopped"},
+		{action: "start", before: stopUnit, status: "Running"},
+		{action: "restart", before: stopUnit, status: "Running"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			fake, mux := newServiceTest(t)
+			if tt.before != nil {
+				tt.before(fake)
+			}
+
+			body := `{"action":"` + tt.action + `"}`
+			for _, path := range []string{"/api/service/action", "/api/services/picoclaw.service/action"} {
+				w := serveAs(mux, auth.RoleOperator, http.MethodPost, path, body)
+				if w.Code != http.StatusOK {
+					t.Fatalf("%s: code = %d, body %s", path, w.Code, w.Body)
+				}
+				if status := decodeStatus(t, w); status.Status != tt.status {
+					t.Errorf("%s: status = %q, want %q", path, status.Status, tt.status)
+				}
+			}
+		})
+	}
+}
+
+func stopUnit(f *systemd.Fake) {
+	f.Set(systemd.UnitStatus{Unit: "picoclaw.service", ActiveState: "inactive", SubState: "dead", LoadState: "loaded"})
+}
+
+func TestServiceActionErrors(t *testing.T) {
+	_, mux := newServiceTest(t)
+
+	w := serveAs(mux, auth.RoleOperator, http.MethodPost, "/api/service/action", `{"action":"reload"}`)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "invalid action") {
+		t.Errorf("invalid action: code = %d, body %s", w.Code, w.Body)
+	}
+
+	w = serveAs(mux, auth.RoleOperator, http.MethodPost, "/api/service/action", `{`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid body: code = %d", w.Code)
+	}
+
+	w = serveAs(mux, auth.RoleViewer, http.MethodPost, "/api/service/action", `{"action":"stop"}`)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("viewer action: code = %d, want 403", w.Code)
+	}
+}
+
+func TestServiceNotAllowed(t *testing.T) {
+	fake, mux := newServiceTest(t)
+
+	for _, tt := range []struct{ method, path, body string }{
+		{http.MethodGet, "/api/services/other.service", ""},
+		{http.MethodPost, "/api/services/other.service/action", `{"action":"stop"}`},
+		{http.MethodGet, "/api/services/missing.service", ""},
+	} {
+		w := serveAs(mux, auth.RoleAdmin, tt.method, tt.path, tt.body)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: code = %d, want 404", tt.method, tt.path, w.Code)
+		}
+	}
+
+	// Юнит вне списка не должен был остановиться
+	status, err := fake.Status(context.Background(), "other.service")
+	if err != nil || status.ActiveState != "active" {
+		t.Errorf("other.service = %+v, %v", status, err)
+	}
+	if err := ControlUnit("other.service", "stop"); err == nil {
+		t.Error("ControlUnit accepted a unit outside the allow-list")
+	}
+}
+
+func TestServiceFailWith(t *testing.T) {
+	fake, mux := newServiceTest(t)
+	fake.FailWith(errors.New("dbus: connection lost"))
+
+	for _, tt := range []struct{ method, path, body string }{
+		{http.MethodGet, "/api/service", ""},
+		{http.MethodGet, "/api/services/worker.service", ""},
+		{http.MethodPost, "/api/service/action", `{"action":"restart"}`},
+	} {
+		w := serveAs(mux, auth.RoleOperator, tt.method, tt.path, tt.body)
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("%s %s: code = %d, want 500", tt.method, tt.path, w.Code)
+		}
+		var body map[string]string
+		json.NewDecoder(w.Body).Decode(&body)
+		if body["error"] != "dbus: connection lost" {
+			t.Errorf("%s %s: error = %q", tt.method, tt.path, body["error"])
+		}
+	}
+
+	w := serveAs(mux, auth.RoleViewer, http.MethodGet, "/api/services", "")
+	var list []ServiceResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	for _, status := range list {
+		if status.Status != "Unknown" || status.Error == "" {
+			t.Errorf("list entry = %+v, want Unknown with error", status)
+		}
+	}
+
+	fake.FailWith(nil)
+	if w := serveAs(mux, auth.RoleViewer, http.MethodGet, "/api/service", ""); w.Code != http.StatusOK {
+		t.Errorf("after FailWith(nil): code = %d", w.Code)
+	}
+}
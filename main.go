@@ -1,13 +1,20 @@
 package main
 
 import (
+	"bufio"
 	"embed"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/waplay/picoclaw-dashboard/api"
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
 	"github.com/waplay/picoclaw-dashboard/websocket"
 )
 
@@ -15,21 +22,80 @@ import (
 var staticFiles embed.FS
 
 func main() {
+	// `picoclaw-dashboard passwd <username> [role] [flags]` manages local users
+	if len(os.Args) > 1 && os.Args[1] == "passwd" {
+		if err := runPasswd(os.Args[2:]); err != nil {
+			log.Fatal("passwd: ", err)
+		}
+		return
+	}
+
+	// Load configuration (defaults < file < env < flags)
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatal("Config error: ", err)
+	}
+
+	// Setup optional authentication
+	authenticator, err := auth.NewAuthenticator(cfg)
+	if err != nil {
+		log.Fatal("Auth error: ", err)
+	}
+	authenticator.RegisterRoutes(http.DefaultServeMux)
+	if authenticator.Enabled() {
+		log.Printf("🔒 Authentication enabled (users: %s)", cfg.Auth.UsersFile)
+	}
+
+	// Setup audit log
+	if err := api.InitAudit(cfg); err != nil {
+		log.Fatal("Audit error: ", err)
+	}
+
+	// Setup metrics history
+	if err := api.InitHistory(cfg); err != nil {
+		log.Fatal("History error: ", err)
+	}
+
+	// Setup systemd backend (D-Bus, falls back to systemctl)
+	if err := api.InitServices(cfg); err != nil {
+		log.Fatal("Service backend error: ", err)
+	}
+
 	// Setup WebSocket hub
-	hub := websocket.NewHub()
+	hub := websocket.NewHub(cfg)
 	go hub.Run()
 
+	// Setup alert rules
+	if err := api.InitAlerts(cfg, hub); err != nil {
+		log.Fatal("Alerts error: ", err)
+	}
+
 	// Setup logs service
-	api.InitLogsService("picoclaw") // systemd unit name
+	api.InitLogsService(cfg, hub)
+
+	// Setup notification channels (alerts, service transitions, log errors)
+	if err := api.InitNotifications(cfg); err != nil {
+		log.Fatal("Notify error: ", err)
+	}
+
+	// Push unit state changes to WebSocket clients and notifications
+	api.StartServiceWatcher(cfg, hub)
 
 	// Setup API routes
-	api.SetupRoutes(hub)
-	api.SetupLogRoutes()    // Log routes
-	api.SetupServiceRoutes() // Service control routes
+	api.SetupRoutes(cfg, hub)
+	api.SetupLogRoutes()        // Log routes
+	api.SetupServiceRoutes()    // Service control routes
+	api.SetupAuditRoutes()      // Audit log
+	api.SetupHistoryRoutes()    // Metrics history
+	api.SetupMetricsRoutes(hub) // Prometheus exporter
+	api.SetupAlertRoutes()      // Alerts and silences
 
-	// Broadcast metrics every 5 seconds
+	// Broadcast metrics periodically
 	go func() {
-		ticker := time.NewTicker(5 * time.Second)
+		ticker := time.NewTicker(cfg.Metrics.BroadcastInterval.Std())
 		defer ticker.Stop()
 		for range ticker.C {
 			health, err := api.GetHealth()
@@ -37,7 +103,17 @@ func main() {
 				log.Printf("⚠️  Error getting health: %v", err)
 				continue
 			}
-			hub.Broadcast(health)
+			api.RecordHealth(health)
+			api.EvaluateHealth(health)
+			hub.Publish(websocket.TopicMetrics, health)
+
+			for _, status := range api.GetAllServiceStatuses() {
+				if status.Error != "" {
+					log.Printf("⚠️  Error getting status of %s: %s", status.Unit, status.Error)
+					continue
+				}
+				api.EvaluateService(status)
+			}
 		}
 	}()
 
@@ -45,21 +121,46 @@ func main() {
 	// Note: In Go 1.22+, specific patterns like GET /api/logs take precedence over / pattern
 	http.Handle("/", http.FileServer(http.FS(staticFiles)))
 
-	// Get Tailscale IP or use default
-	port := "8080"
-	addr := fmt.Sprintf(":%s", port)
+	addr := cfg.Addr()
 
 	log.Printf("🚀 PicoClaw Dashboard starting on %s", addr)
 	log.Printf("📊 Metrics: %s/api/health | 🔌 WebSocket: %s/ws", addr, addr)
 
 	server := &http.Server{
 		Addr:         addr,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Handler:      api.InstrumentHTTP(authenticator.Middleware(http.DefaultServeMux)),
+		ReadTimeout:  cfg.Server.ReadTimeout.Std(),
+		WriteTimeout: cfg.Server.WriteTimeout.Std(),
+		IdleTimeout:  cfg.Server.IdleTimeout.Std(),
 	}
 
 	if err := server.ListenAndServe(); err != nil {
 		log.Fatal("Server error:", err)
 	}
 }
+
+// runPasswd creates a user or changes their password and role. The password is read from stdin.
+func runPasswd(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New("usage: picoclaw-dashboard passwd <username> [viewer|operator|admin] [flags]")
+	}
+	username, args := args[0], args[1:]
+
+	role := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		role, args = args[0], args[1:]
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Password for %s: ", username)
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return err
+	}
+
+	return auth.SetPassword(cfg, username, strings.TrimRight(password, "\r\n"), role)
+}
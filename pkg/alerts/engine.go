@@ -0,0 +1,241 @@
+package alerts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// State — состояние алерта
+type State string
+
+const (
+	StateInactive State = "inactive" // условие не выполняется
+	StatePending  State = "pending"  // условие выполняется, но меньше чем For
+	StateFiring   State = "firing"
+	StateResolved State = "resolved" // был firing, условие больше не выполняется
+)
+
+var ErrSilenceNotFound = errors.New("silence not found")
+
+// Alert — текущее состояние правила
+type Alert struct {
+	Rule        Rule       `json:"rule"`
+	For         string     `json:"for"`
+	State       State      `json:"state"`
+	Value       float64    `json:"value"`
+	ActiveSince *time.Time `json:"active_since,omitempty"` // когда условие начало выполняться
+	FiredAt     *time.Time `json:"fired_at,omitempty"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	LastEval    time.Time  `json:"last_evaluated"`
+	Silenced    bool       `json:"silenced"`
+}
+
+// Event — смена состояния алерта
+type Event struct {
+	Alert    Alert     `json:"alert"`
+	Previous State     `json:"previous"`
+	Time     time.Time `json:"time"`
+}
+
+// Silence — заглушка для правила (или всех правил при Rule == "*") до Until
+type Silence struct {
+	ID        string    `json:"id"`
+	Rule      string    `json:"rule"`
+	Until     time.Time `json:"until"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Engine вычисляет правила по каждому замеру и хранит их состояния
+type Engine struct {
+	mu          sync.Mutex
+	alerts      []*Alert
+	silences    map[string]Silence
+	subscribers []func(Event)
+	now         func() time.Time // часы для заглушек; в тестах подменяются
+}
+
+// NewEngine разбирает правила из конфигурации
+func NewEngine(cfg *config.Config) (*Engine, error) {
+	e := &Engine{silences: make(map[string]Silence), now: time.Now}
+
+	seen := map[string]bool{}
+	for _, rc := range cfg.Alerts.Rules {
+		rule, err := ParseRule(rc)
+		if err != nil {
+			return nil, err
+		}
+		if seen[rule.Name] {
+			return nil, errors.New("alert rule " + rule.Name + ": duplicate name")
+		}
+		seen[rule.Name] = true
+
+		e.alerts = append(e.alerts, &Alert{
+			Rule:  rule,
+			For:   rule.For.String(),
+			State: StateInactive,
+		})
+	}
+
+	return e, nil
+}
+
+// Subscribe регистрирует обработчик смены состояний. Вызывается синхронно из Evaluate
+func (e *Engine) Subscribe(fn func(Event)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.subscribers = append(e.subscribers, fn)
+}
+
+// Evaluate применяет замер. Правила, метрик которых нет в sample, не трогаются
+func (e *Engine) Evaluate(sample map[string]float64, now time.Time) {
+	e.mu.Lock()
+	var events []Event
+	for _, a := range e.alerts {
+		value, ok := sample[a.Rule.Metric]
+		if !ok {
+			continue
+		}
+		prev := a.State
+		a.Value = value
+		a.LastEval = now
+		a.Silenced = e.silencedLocked(a.Rule.Name, now)
+
+		if a.Rule.Matches(value) {
+			if a.ActiveSince == nil {
+				since := now
+				a.ActiveSince = &since
+			}
+			if now.Sub(*a.ActiveSince) >= a.Rule.For {
+				if a.State != StateFiring {
+					fired := now
+					a.FiredAt = &fired
+					a.ResolvedAt = nil
+				}
+				a.State = StateFiring
+			} else {
+				a.State = StatePending
+			}
+		} else {
+			a.ActiveSince = nil
+			switch a.State {
+			case StateFiring:
+				resolved := now
+				a.ResolvedAt = &resolved
+				a.State = StateResolved
+			case StatePending:
+				a.State = StateInactive
+			}
+		}
+
+		if a.State != prev {
+			events = append(events, Event{Alert: *a, Previous: prev, Time: now})
+		}
+	}
+	subscribers := make([]func(Event), len(e.subscribers))
+	copy(subscribers, e.subscribers)
+	e.mu.Unlock()
+
+	for _, ev := range events {
+		for _, fn := range subscribers {
+			fn(ev)
+		}
+	}
+}
+
+// Alerts возвращает состояния всех правил
+func (e *Engine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	result := make([]Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		alert := *a
+		alert.Silenced = e.silencedLocked(a.Rule.Name, now)
+		result = append(result, alert)
+	}
+	return result
+}
+
+// AddSilence глушит правило (или все правила при rule == "*") до until
+func (e *Engine) AddSilence(rule string, until time.Time, comment, createdBy string) (Silence, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if rule != "*" && !e.hasRuleLocked(rule) {
+		return Silence{}, errors.New("unknown alert rule: " + rule)
+	}
+	now := e.now()
+	if !until.After(now) {
+		return Silence{}, errors.New("silence must end in the future")
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	s := Silence{
+		ID:        hex.EncodeToString(b),
+		Rule:      rule,
+		Until:     until,
+		Comment:   comment,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+	}
+	e.silences[s.ID] = s
+	return s, nil
+}
+
+// RemoveSilence снимает заглушку
+func (e *Engine) RemoveSilence(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.silences[id]; !ok {
+		return ErrSilenceNotFound
+	}
+	delete(e.silences, id)
+	return nil
+}
+
+// Silences возвращает действующие заглушки
+func (e *Engine) Silences() []Silence {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	result := []Silence{}
+	for id, s := range e.silences {
+		if !s.Until.After(now) {
+			delete(e.silences, id)
+			continue
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Until.Before(result[j].Until) })
+	return result
+}
+
+func (e *Engine) silencedLocked(rule string, now time.Time) bool {
+	for _, s := range e.silences {
+		if (s.Rule == rule || s.Rule == "*") && s.Until.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) hasRuleLocked(name string) bool {
+	for _, a := range e.alerts {
+		if a.Rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package alerts
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// fakeClock — часы теста; Engine.now читает их вместо time.Now
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time                  { return c.t }
+func (c *fakeClock) advance(d time.Duration)         { c.t = c.t.Add(d) }
+func (c *fakeClock) after(d time.Duration) time.Time { return c.t.Add(d) }
+
+func newTestEngine(t *testing.T, rules ...config.AlertRule) (*Engine, *fakeClock, *[]Event) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Alerts.Rules = rules
+	e, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{t: time.Date(2026, 2, 21, 10, 0, 0, 0, time.UTC)}
+	e.now = clock.now
+
+	var events []Event
+	e.Subscribe(func(ev Event) { events = append(events, ev) })
+	return e, clock, &events
+}
+
+func alertByName(e *Engine, name string) Alert {
+	for _, a := range e.Alerts() {
+		if a.Rule.Name == name {
+			return a
+		}
+	}
+	return Alert{}
+}
+
+func TestEvaluateStateMachine(t *testing.T) {
+	type step struct {
+		after time.Duration // от предыдущего шага
+		value float64
+		state State
+		event bool // на шаге сменилось состояние
+	}
+	tests := []struct {
+		name  string
+		rule  config.AlertRule
+		steps []step
+	}{
+		{
+			name: "fires at once without for",
+			rule: config.AlertRule{Name: "cpu", Expr: "cpu.usage_percent > 90"},
+			steps: []step{
+				{0, 50, StateInactive, false},
+				{time.Second, 95, StateFiring, true},
+				{time.Second, 96, StateFiring, false},
+				{time.Second, 10, StateResolved, true},
+				{time.Second, 10, StateResolved, false},
+				{time.Second, 99, StateFiring, true},
+			},
+		},
+		{
+			name: "pending until for elapses",
+			rule: config.AlertRule{Name: "cpu", Expr: "cpu.usage_percent > 90", For: config.Duration(time.Minute)},
+			steps: []step{
+				{0, 95, StatePending, true},
+				{30 * time.Second, 95, StatePending, false},
+				{30 * time.Second, 95, StateFiring, true},
+				{time.Minute, 95, StateFiring, false},
+				{time.Second, 50, StateResolved, true},
+			},
+		},
+		{
+			name: "pending drops back to inactive",
+			rule: config.AlertRule{Name: "cpu", Expr: "cpu.usage_percent >= 90", For: config.Duration(time.Minute)},
+			steps: []step{
+				{0, 90, StatePending, true},
+				{30 * time.Second, 89, StateInactive, true},
+				// Условие снова выполняется — for отсчитывается заново
+				{time.Second, 90, StatePending, true},
+				{59 * time.Second, 90, StatePending, false},
+				{time.Second, 90, StateFiring, true},
+			},
+		},
+		{
+			name: "service state rule",
+			rule: config.AlertRule{Name: "down", Expr: "service.active == 0", For: config.Duration(10 * time.Second)},
+			steps: []step{
+				{0, 1, StateInactive, false},
+				{5 * time.Second, 0, StatePending, true},
+				{10 * time.Second, 0, StateFiring, true},
+				{5 * time.Second, 1, StateResolved, true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, clock, events := newTestEngine(t, tt.rule)
+			metric := strings.Fields(tt.rule.Expr)[0]
+			for i, s := range tt.steps {
+				clock.advance(s.after)
+				before := len(*events)
+				e.Evaluate(map[string]float64{metric: s.value}, clock.now())
+
+				a := alertByName(e, tt.rule.Name)
+				if a.State != s.state {
+					t.Fatalf("step %d (value %v): state = %s, want %s", i, s.value, a.State, s.state)
+				}
+				if got := len(*events) > before; got != s.event {
+					t.Fatalf("step %d: event = %v, want %v", i, got, s.event)
+				}
+				if s.event {
+					ev := (*events)[len(*events)-1]
+					if ev.Alert.State != s.state || !ev.Time.Equal(clock.now()) {
+						t.Errorf("step %d: event %s at %v", i, ev.Alert.State, ev.Time)
+					}
+				}
+				switch a.State {
+				case StateFiring:
+					if a.FiredAt == nil || a.ResolvedAt != nil {
+						t.Errorf("step %d: firing with fired_at=%v resolved_at=%v", i, a.FiredAt, a.ResolvedAt)
+					}
+				case StateResolved:
+					if a.ResolvedAt == nil || a.ActiveSince != nil {
+						t.Errorf("step %d: resolved with resolved_at=%v active_since=%v", i, a.ResolvedAt, a.ActiveSince)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateSkipsMissingMetrics(t *testing.T) {
+	e, clock, events := newTestEngine(t, config.AlertRule{Name: "disk", Expr: "disk.used_percent > 90"})
+
+	e.Evaluate(map[string]float64{"cpu.usage_percent": 99}, clock.now())
+	if a := alertByName(e, "disk"); a.State != StateInactive || !a.LastEval.IsZero() || len(*events) != 0 {
+		t.Errorf("rule without its metric was evaluated: %+v", a)
+	}
+}
+
+func TestSilences(t *testing.T) {
+	e, clock, events := newTestEngine(t,
+		config.AlertRule{Name: "cpu", Expr: "cpu.usage_percent > 90"},
+		config.AlertRule{Name: "mem", Expr: "memory.used_percent > 90"},
+	)
+	sample := map[string]float64{"cpu.usage_percent": 95, "memory.used_percent": 95}
+
+	if _, err := e.AddSilence("nope", clock.after(time.Hour), "", "admin"); err == nil {
+		t.Error("silence for an unknown rule accepted")
+	}
+	if _, err := e.AddSilence("cpu", clock.now(), "", "admin"); err == nil {
+		t.Error("silence ending now accepted")
+	}
+
+	s, err := e.AddSilence("cpu", clock.after(time.Hour), "maintenance", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Evaluate(sample, clock.now())
+
+	// Заглушка не мешает смене состояния, только помечает алерт
+	if len(*events) != 2 {
+		t.Fatalf("got %d events, want 2", len(*events))
+	}
+	if a := alertByName(e, "cpu"); a.State != StateFiring || !a.Silenced {
+		t.Errorf("cpu = %s silenced=%v, want firing and silenced", a.State, a.Silenced)
+	}
+	if a := alertByName(e, "mem"); a.Silenced {
+		t.Error("mem is silenced by the cpu silence")
+	}
+
+	// Заглушка истекает
+	clock.advance(time.Hour)
+	if a := alertByName(e, "cpu"); a.Silenced {
+		t.Error("cpu is still silenced after the silence expired")
+	}
+	if got := e.Silences(); len(got) != 0 {
+		t.Errorf("expired silences are listed: %+v", got)
+	}
+	if err := e.RemoveSilence(s.ID); err != ErrSilenceNotFound {
+		t.Errorf("RemoveSilence of an expired silence = %v, want ErrSilenceNotFound", err)
+	}
+
+	// "*" глушит все правила, пока её не снимут
+	all, err := e.AddSilence("*", clock.after(time.Minute), "", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Evaluate(sample, clock.now())
+	for _, a := range e.Alerts() {
+		if !a.Silenced {
+			t.Errorf("%s is not silenced by *", a.Rule.Name)
+		}
+	}
+	if err := e.RemoveSilence(all.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range e.Alerts() {
+		if a.Silenced {
+			t.Errorf("%s is silenced after the silence was removed", a.Rule.Name)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule(config.AlertRule{Name: "cpu", Expr: " cpu.usage_percent >= 85.5 ", For: config.Duration(time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Metric != "cpu.usage_percent" || rule.Op != ">=" || rule.Threshold != 85.5 || rule.For != time.Minute || rule.Severity != "warning" {
+		t.Errorf("rule = %+v", rule)
+	}
+
+	for _, rc := range []config.AlertRule{
+		{Expr: "cpu.usage_percent > 1"},
+		{Name: "x", Expr: "cpu.usage_percent >> 1"},
+		{Name: "x", Expr: "gpu.usage > 1"},
+		{Name: "x", Expr: "cpu.usage_percent > 1", For: config.Duration(-time.Second)},
+	} {
+		if _, err := ParseRule(rc); err == nil {
+			t.Errorf("ParseRule(%+v) accepted", rc)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Alerts.Rules = []config.AlertRule{{Name: "a", Expr: "cpu.cores > 1"}, {Name: "a", Expr: "cpu.cores > 2"}}
+	if _, err := NewEngine(cfg); err == nil {
+		t.Error("duplicate rule names accepted")
+	}
+}
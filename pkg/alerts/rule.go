@@ -0,0 +1,108 @@
+package alerts
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// Metrics — метрики, доступные в правилах
+var Metrics = []string{
+	"cpu.usage_percent",
+	"cpu.cores",
+	"memory.used_percent",
+	"memory.used_bytes",
+	"memory.available_bytes",
+	"disk.used_percent",
+	"disk.used_bytes",
+	"disk.free_bytes",
+	"uptime.seconds",
+	"service.active",  // 1/0
+	"service.running", // 1/0
+	"service.loaded",  // 1/0
+	"service.enabled", // 1/0
+}
+
+// Rule — разобранное правило алерта
+type Rule struct {
+	Name        string        `json:"name"`
+	Expr        string        `json:"expr"`
+	Metric      string        `json:"metric"`
+	Op          string        `json:"op"`
+	Threshold   float64       `json:"threshold"`
+	For         time.Duration `json:"-"`
+	Severity    string        `json:"severity"`
+	Description string        `json:"description,omitempty"`
+}
+
+var exprPattern = regexp.MustCompile(`^\s*([a-z_.]+)\s*(>=|<=|==|!=|>|<)\s*(-?[0-9]+(?:\.[0-9]+)?)\s*$`)
+
+// ParseRule разбирает правило из конфигурации. Формат выражения: "<метрика> <оператор> <число>"
+func ParseRule(rc config.AlertRule) (Rule, error) {
+	if rc.Name == "" {
+		return Rule{}, fmt.Errorf("alert rule: name is required")
+	}
+
+	m := exprPattern.FindStringSubmatch(rc.Expr)
+	if m == nil {
+		return Rule{}, fmt.Errorf("alert rule %s: invalid expr %q, expected \"<metric> <op> <number>\"", rc.Name, rc.Expr)
+	}
+	if !knownMetric(m[1]) {
+		return Rule{}, fmt.Errorf("alert rule %s: unknown metric %q (available: %s)", rc.Name, m[1], strings.Join(Metrics, ", "))
+	}
+	threshold, err := strconv.ParseFloat(m[3], 64)
+	if err != nil {
+		return Rule{}, fmt.Errorf("alert rule %s: invalid threshold: %w", rc.Name, err)
+	}
+	if rc.For < 0 {
+		return Rule{}, fmt.Errorf("alert rule %s: for must not be negative", rc.Name)
+	}
+
+	severity := rc.Severity
+	if severity == "" {
+		severity = "warning"
+	}
+
+	return Rule{
+		Name:        rc.Name,
+		Expr:        strings.TrimSpace(rc.Expr),
+		Metric:      m[1],
+		Op:          m[2],
+		Threshold:   threshold,
+		For:         rc.For.Std(),
+		Severity:    severity,
+		Description: rc.Description,
+	}, nil
+}
+
+// Matches проверяет условие правила для значения
+func (r Rule) Matches(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case "==":
+		return value == r.Threshold
+	case "!=":
+		return value != r.Threshold
+	}
+	return false
+}
+
+func knownMetric(name string) bool {
+	for _, m := range Metrics {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
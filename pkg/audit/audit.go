@@ -0,0 +1,324 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// Действия, которые пишутся в журнал аудита
+const (
+	ActionServiceStart   = "service.start"
+	ActionServiceStop    = "service.stop"
+	ActionServiceRestart = "service.restart"
+	ActionFileWrite      = "file.write"
+	ActionFileDelete     = "file.delete"
+	ActionDirCreate      = "directory.create"
+	ActionAlertSilence   = "alert.silence"
+	ActionAlertUnsilence = "alert.unsilence"
+)
+
+// Результат действия
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Event — одна запись журнала аудита
+type Event struct {
+	ID      string            `json:"id"`
+	Time    time.Time         `json:"time"`
+	Actor   string            `json:"actor"`
+	Role    string            `json:"role,omitempty"`
+	Auth    string            `json:"auth,omitempty"` // session, token, none
+	IP      string            `json:"ip"`
+	Action  string            `json:"action"`
+	Target  string            `json:"target"`
+	Result  string            `json:"result"`
+	Error   string            `json:"error,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Filter — параметры выборки из журнала
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	Actor  string
+	Action string
+	Offset int
+	Limit  int
+}
+
+// Logger — append-only журнал в JSONL с ротацией по размеру.
+// Текущий файл — path, архивы — path.1 (новее) … path.N (старее)
+type Logger struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewLogger открывает журнал из конфигурации. При выключенном аудите возвращает nil:
+// все методы nil-логгера — no-op
+func NewLogger(cfg *config.Config) (*Logger, error) {
+	if !cfg.Audit.Enabled {
+		return nil, nil
+	}
+
+	l := &Logger{
+		path:     cfg.Audit.File,
+		maxSize:  int64(cfg.Audit.MaxSizeMB) * 1024 * 1024,
+		maxFiles: cfg.Audit.MaxFiles,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return nil, fmt.Errorf("create audit dir: %w", err)
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *Logger) open() error {
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat audit log: %w", err)
+	}
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// FromRequest заполняет кто/откуда по HTTP запросу
+func FromRequest(r *http.Request, action, target string) Event {
+	e := Event{
+		Time:   time.Now().UTC(),
+		Actor:  "unknown",
+		IP:     clientIP(r),
+		Action: action,
+		Target: target,
+		Result: ResultSuccess,
+	}
+	if id, ok := auth.FromContext(r.Context()); ok {
+		e.Actor = id.Username
+		e.Role = string(id.Role)
+		e.Auth = id.Method
+	}
+	return e
+}
+
+// Record дописывает событие в журнал
+func (l *Logger) Record(e Event) error {
+	if l == nil {
+		return nil
+	}
+
+	if e.ID == "" {
+		e.ID = newID()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(data)) > l.maxSize {
+		if err := l.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
+
+// rotateLocked сдвигает архивы: path.N-1 → path.N, …, path → path.1
+func (l *Logger) rotateLocked() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("close audit log: %w", err)
+	}
+
+	os.Remove(l.archive(l.maxFiles))
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		os.Rename(l.archive(i), l.archive(i+1))
+	}
+	if l.maxFiles > 0 {
+		if err := os.Rename(l.path, l.archive(1)); err != nil {
+			return fmt.Errorf("rotate audit log: %w", err)
+		}
+	} else {
+		os.Remove(l.path)
+	}
+
+	return l.open()
+}
+
+func (l *Logger) archive(i int) string {
+	return fmt.Sprintf("%s.%d", l.path, i)
+}
+
+// Query возвращает события по фильтру (новые первыми) и общее количество совпадений
+func (l *Logger) Query(filter Filter) ([]Event, int, error) {
+	if filter.Offset < 0 || filter.Limit < 0 {
+		return nil, 0, fmt.Errorf("invalid offset %d or limit %d", filter.Offset, filter.Limit)
+	}
+	if l == nil {
+		return []Event{}, 0, nil
+	}
+
+	// Файлы открываются под мьютексом, чтобы ротация не сдвинула их между открытиями;
+	// открытые дескрипторы переживают переименование, читать можно без блокировки
+	l.mu.Lock()
+	var files []*os.File
+	var err error
+	for i := 0; i <= l.maxFiles && err == nil; i++ {
+		path := l.path
+		if i > 0 {
+			path = l.archive(i)
+		}
+		var f *os.File
+		if f, err = openEvents(path); f != nil {
+			files = append(files, f)
+		}
+	}
+	l.mu.Unlock()
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var matched []Event
+	for _, f := range files {
+		events, err := readEvents(f)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, e := range events {
+			if filter.matches(e) {
+				matched = append(matched, e)
+			}
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Time.After(matched[j].Time)
+	})
+
+	total := len(matched)
+	if filter.Offset >= total {
+		return []Event{}, total, nil
+	}
+	end := total
+	if filter.Limit > 0 && filter.Offset+filter.Limit < total {
+		end = filter.Offset + filter.Limit
+	}
+
+	return matched[filter.Offset:end], total, nil
+}
+
+// Close закрывает файл журнала
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+func (f Filter) matches(e Event) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && e.Time.After(f.To) {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	return true
+}
+
+// openEvents открывает файл журнала; отсутствующий файл — nil без ошибки
+func openEvents(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	return f, nil
+}
+
+// readEvents читает события из JSONL файла
+func readEvents(f *os.File) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // битая строка (например, оборванная запись) не должна ломать выборку
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	return events, nil
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+func newTestLogger(t *testing.T) *Logger {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Audit.Enabled = true
+	cfg.Audit.File = filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func TestQueryPaging(t *testing.T) {
+	l := newTestLogger(t)
+	start := time.Date(2026, 2, 21, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		if err := l.Record(Event{Time: start.Add(time.Duration(i) * time.Minute), Actor: "admin", Action: ActionServiceRestart}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		offset, limit int
+		want          int
+	}{
+		{0, 2, 2},
+		{4, 2, 1},
+		{5, 2, 0},
+		{math.MaxInt, 2, 0},
+		{0, 0, 5},
+	}
+	for _, tt := range tests {
+		events, total, err := l.Query(Filter{Offset: tt.offset, Limit: tt.limit})
+		if err != nil || total != 5 || len(events) != tt.want {
+			t.Errorf("offset %d limit %d: %d events, total %d, %v; want %d", tt.offset, tt.limit, len(events), total, err, tt.want)
+		}
+	}
+
+	events, _, _ := l.Query(Filter{Limit: 1})
+	if len(events) != 1 || !events[0].Time.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("first page = %+v, want the newest event", events)
+	}
+
+	for _, f := range []Filter{{Offset: -1}, {Offset: math.MinInt, Limit: 3}, {Limit: -1}} {
+		if _, _, err := l.Query(f); err == nil {
+			t.Errorf("Query(%+v) accepted a negative offset or limit", f)
+		}
+	}
+}
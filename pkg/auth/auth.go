@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// SessionCookie — имя cookie с ID сессии
+const SessionCookie = "picoclaw_session"
+
+// LoginPage — страница входа (отдаётся без авторизации)
+const LoginPage = "/static/login.html"
+
+// Способы аутентификации
+const (
+	MethodNone    = "none" // авторизация выключена
+	MethodSession = "session"
+	MethodToken   = "token"
+)
+
+// Identity — кто выполняет запрос
+type Identity struct {
+	Username string `json:"username"`
+	Role     Role   `json:"role"`
+	Method   string `json:"method"`
+	TokenID  string `json:"token_id,omitempty"`
+}
+
+type contextKey struct{}
+
+// WithIdentity кладёт Identity в контекст
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext достаёт Identity из контекста запроса
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(Identity)
+	return id, ok
+}
+
+// publicPaths доступны без входа: страница логина, её стили и сам логин
+var publicPaths = map[string]bool{
+	LoginPage:           true,
+	"/static/style.css": true,
+	"/api/auth/login":   true,
+	"/favicon.ico":      true,
+}
+
+// Authenticator — опциональный слой авторизации над всеми роутами
+type Authenticator struct {
+	enabled       bool
+	cookieSecure  bool
+	anonymousRole Role // роль запросов при выключенной авторизации
+	store         *Store
+	sessions      *Sessions
+}
+
+// NewAuthenticator создаёт слой авторизации из конфигурации
+func NewAuthenticator(cfg *config.Config) (*Authenticator, error) {
+	anonymousRole, err := ParseRole(cfg.Auth.AnonymousRole)
+	if err != nil {
+		return nil, fmt.Errorf("auth.anonymous_role: %w", err)
+	}
+
+	a := &Authenticator{
+		enabled:       cfg.Auth.Enabled,
+		cookieSecure:  cfg.Auth.CookieSecure,
+		anonymousRole: anonymousRole,
+	}
+	if !a.enabled {
+		return a, nil
+	}
+
+	store, err := OpenStore(cfg.Auth.UsersFile)
+	if err != nil {
+		return nil, err
+	}
+	if store.UserCount() == 0 {
+		return nil, fmt.Errorf("auth is enabled but %s has no users, create one with: picoclaw-dashboard passwd <username>", cfg.Auth.UsersFile)
+	}
+
+	a.store = store
+	a.sessions = NewSessions(cfg.Auth.SessionTTL.Std())
+	return a, nil
+}
+
+// Enabled сообщает, включена ли авторизация
+func (a *Authenticator) Enabled() bool {
+	return a.enabled
+}
+
+// Middleware оборачивает весь mux: API, WebSocket и статику
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.enabled {
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), Identity{
+				Username: "anonymous",
+				Role:     a.anonymousRole,
+				Method:   MethodNone,
+			})))
+			return
+		}
+
+		if id, ok := a.identify(r); ok {
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+			return
+		}
+
+		if publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// API и WebSocket получают 401, браузер — редирект на страницу входа
+		if strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/ws" {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		http.Redirect(w, r, LoginPage+"?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+	})
+}
+
+// identify проверяет bearer токен, затем cookie сессии
+func (a *Authenticator) identify(r *http.Request) (Identity, bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		raw, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return Identity{}, false
+		}
+		token, ok := a.store.LookupToken(strings.TrimSpace(raw))
+		if !ok {
+			return Identity{}, false
+		}
+		user, ok := a.store.User(token.Username)
+		if !ok {
+			return Identity{}, false
+		}
+		return Identity{Username: user.Username, Role: user.Role, Method: MethodToken, TokenID: token.ID}, true
+	}
+
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return Identity{}, false
+	}
+	session, ok := a.sessions.Get(cookie.Value)
+	if !ok {
+		return Identity{}, false
+	}
+	user, ok := a.store.User(session.Username)
+	if !ok {
+		a.sessions.Delete(session.ID)
+		return Identity{}, false
+	}
+	return Identity{Username: user.Username, Role: user.Role, Method: MethodSession}, true
+}
+
+// SetPassword создаёт или обновляет пользователя в файле из конфигурации (для CLI)
+func SetPassword(cfg *config.Config, username, password, role string) error {
+	var r Role
+	if role != "" {
+		parsed, err := ParseRole(role)
+		if err != nil {
+			return err
+		}
+		r = parsed
+	}
+
+	store, err := OpenStore(cfg.Auth.UsersFile)
+	if err != nil {
+		return err
+	}
+	if err := store.SetPassword(username, password, r); err != nil {
+		return err
+	}
+
+	user, _ := store.User(username)
+	log.Printf("🔑 Password set for user %s (role %s) in %s", username, user.Role, cfg.Auth.UsersFile)
+	return nil
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// newTestAuthenticator включает авторизацию с одним пользователем alice (operator)
+func newTestAuthenticator(t *testing.T) *Authenticator {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	cfg.Auth.UsersFile = filepath.Join(t.TempDir(), "users.json")
+
+	store, err := OpenStore(cfg.Auth.UsersFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetPassword("alice", "correct-horse", RoleOperator); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewAuthenticator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// serveMiddleware пропускает запрос через Middleware и возвращает ответ и Identity, дошедшую до обработчика
+func serveMiddleware(a *Authenticator, r *http.Request) (*httptest.ResponseRecorder, Identity, bool) {
+	var (
+		id      Identity
+		reached bool
+	)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, reached = FromContext(r.Context())
+	})
+	w := httptest.NewRecorder()
+	a.Middleware(next).ServeHTTP(w, r)
+	return w, id, reached
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.AnonymousRole = "viewer"
+	a, err := NewAuthenticator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, id, ok := serveMiddleware(a, httptest.NewRequest(http.MethodGet, "/api/logs", nil))
+	if w.Code != http.StatusOK || !ok {
+		t.Fatalf("code = %d, identity set = %v", w.Code, ok)
+	}
+	if id.Username != "anonymous" || id.Role != RoleViewer || id.Method != MethodNone {
+		t.Errorf("identity = %+v, want anonymous viewer", id)
+	}
+
+	cfg.Auth.AnonymousRole = "root"
+	if _, err := NewAuthenticator(cfg); err == nil {
+		t.Error("unknown anonymous_role accepted")
+	}
+}
+
+func TestNewAuthenticatorRequiresUsers(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	cfg.Auth.UsersFile = filepath.Join(t.TempDir(), "users.json")
+	if _, err := NewAuthenticator(cfg); err == nil {
+		t.Error("auth enabled with an empty users file")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a := newTestAuthenticator(t)
+
+	session, err := a.sessions.Create("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := a.sessions.Create("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	a.sessions.sessions[expired.ID] = expired
+
+	raw, token, err := a.store.CreateToken("alice", "ci", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedToken, err := a.store.CreateToken("alice", "old", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.store.RevokeToken("alice", revokedToken.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		cookie string
+		header string
+		code   int
+		method string // пусто — запрос не должен дойти до обработчика с Identity
+	}{
+		{name: "session", path: "/api/logs", cookie: session.ID, code: http.StatusOK, method: MethodSession},
+		{name: "bearer token", path: "/api/logs", header: "Bearer " + raw, code: http.StatusOK, method: MethodToken},
+		{name: "no credentials", path: "/api/logs", code: http.StatusUnauthorized},
+		{name: "no credentials on websocket", path: "/ws", code: http.StatusUnauthorized},
+		{name: "unknown session", path: "/api/logs", cookie: "nope", code: http.StatusUnauthorized},
+		{name: "expired session", path: "/api/logs", cookie: expired.ID, code: http.StatusUnauthorized},
+		{name: "bad token", path: "/api/logs", header: "Bearer " + TokenPrefix + "nope", code: http.StatusUnauthorized},
+		{name: "revoked token", path: "/api/logs", header: "Bearer " + revoked, code: http.StatusUnauthorized},
+		// Чужая схема не подменяется cookie
+		{name: "basic scheme", path: "/api/logs", header: "Basic YWxpY2U6eA==", cookie: session.ID, code: http.StatusUnauthorized},
+		{name: "page without session", path: "/files?dir=x", code: http.StatusFound},
+		{name: "public path", path: LoginPage, code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: SessionCookie, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			w, id, ok := serveMiddleware(a, r)
+			if w.Code != tt.code {
+				t.Fatalf("code = %d, want %d", w.Code, tt.code)
+			}
+			if tt.method == "" {
+				if ok {
+					t.Errorf("handler got identity %+v", id)
+				}
+			} else if id.Username != "alice" || id.Role != RoleOperator || id.Method != tt.method {
+				t.Errorf("identity = %+v, want alice/operator via %s", id, tt.method)
+			}
+
+			switch tt.code {
+			case http.StatusUnauthorized:
+				var body map[string]string
+				if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+					t.Fatalf("decode 401: %v", err)
+				}
+				if w.Header().Get("Content-Type") != "application/json" || len(body) != 1 || body["error"] != "authentication required" {
+					t.Errorf("401 body = %v", body)
+				}
+			case http.StatusFound:
+				if loc := w.Header().Get("Location"); loc != LoginPage+"?next=%2Ffiles%3Fdir%3Dx" {
+					t.Errorf("Location = %q", loc)
+				}
+			}
+		})
+	}
+
+	if id := identityFor(a, "Bearer "+raw); id.TokenID != token.ID {
+		t.Errorf("token identity = %+v, want token_id %s", id, token.ID)
+	}
+	if _, ok := a.sessions.Get(expired.ID); ok {
+		t.Error("expired session is still stored")
+	}
+}
+
+func identityFor(a *Authenticator, header string) Identity {
+	r := httptest.NewRequest(http.MethodGet, "/api/logs", nil)
+	r.Header.Set("Authorization", header)
+	_, id, _ := serveMiddleware(a, r)
+	return id
+}
+
+func TestSessions(t *testing.T) {
+	s := NewSessions(time.Hour)
+	session, err := s.Create("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := s.Get(session.ID); !ok || got.Username != "alice" {
+		t.Fatalf("Get = %+v, %v", got, ok)
+	}
+	s.Delete(session.ID)
+	if _, ok := s.Get(session.ID); ok {
+		t.Error("deleted session is still valid")
+	}
+
+	// Истёкшие сессии вычищаются при создании новых
+	short := NewSessions(-time.Second)
+	old, _ := short.Create("alice")
+	short.Create("bob")
+	if _, ok := short.sessions[old.ID]; ok {
+		t.Error("expired session was not pruned")
+	}
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type createTokenRequest struct {
+	Name      string `json:"name"`
+	ExpiresIn string `json:"expires_in"` // например "720h", пусто — бессрочный
+}
+
+// RegisterRoutes регистрирует роуты /api/auth/*
+func (a *Authenticator) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			a.login(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/auth/logout", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			a.logout(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/auth/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			a.me(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/auth/tokens", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			a.listTokens(w, r)
+		case http.MethodPost:
+			a.createToken(w, r)
+		case http.MethodDelete:
+			a.revokeToken(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (a *Authenticator) login(w http.ResponseWriter, r *http.Request) {
+	if !a.enabled {
+		writeError(w, http.StatusNotFound, "authentication is disabled")
+		return
+	}
+
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := a.store.Authenticate(req.Username, req.Password)
+	if err != nil {
+		log.Printf("🔒 Failed login for %q from %s", req.Username, r.RemoteAddr)
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	session, err := a.sessions.Create(user.Username)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    session.ID,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   a.cookieSecure,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Identity{Username: user.Username, Role: user.Role, Method: MethodSession})
+}
+
+func (a *Authenticator) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookie); err == nil && a.sessions != nil {
+		a.sessions.Delete(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   a.cookieSecure,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+func (a *Authenticator) me(w http.ResponseWriter, r *http.Request) {
+	id, _ := FromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"auth_enabled": a.enabled,
+		"identity":     id,
+		"permissions":  id.Role.Permissions(),
+	})
+}
+
+func (a *Authenticator) listTokens(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tokens": a.store.Tokens(id.Username),
+	})
+}
+
+func (a *Authenticator) createToken(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req createTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		writeError(w, http.StatusBadRequest, "Invalid request body, name is required")
+		return
+	}
+
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "Invalid expires_in")
+			return
+		}
+		ttl = d
+	}
+
+	raw, token, err := a.store.CreateToken(id.Username, req.Name, ttl)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":   raw, // показывается только один раз
+		"details": token,
+	})
+}
+
+func (a *Authenticator) revokeToken(w http.ResponseWriter, r *http.Request) {
+	id, ok := a.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	tokenID := r.URL.Query().Get("id")
+	if tokenID == "" {
+		writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	if err := a.store.RevokeToken(id.Username, tokenID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrTokenNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success", "id": tokenID})
+}
+
+// requireUser — управление токенами доступно только вошедшим пользователям
+func (a *Authenticator) requireUser(w http.ResponseWriter, r *http.Request) (Identity, bool) {
+	if !a.enabled {
+		writeError(w, http.StatusNotFound, "authentication is disabled")
+		return Identity{}, false
+	}
+	id, ok := FromContext(r.Context())
+	if !ok || id.Method == MethodNone {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return Identity{}, false
+	}
+	return id, true
+}
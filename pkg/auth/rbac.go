@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Role — роль пользователя
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+// Permission — право на группу эндпоинтов
+type Permission string
+
+const (
+	PermHealthRead     Permission = "health:read"     // GET /api/health, /ws
+	PermLogsRead       Permission = "logs:read"       // GET /api/logs*
+	PermServiceRead    Permission = "service:read"    // GET /api/service
+	PermServiceControl Permission = "service:control" // POST /api/service/action
+	PermFilesRead      Permission = "files:read"      // GET /api/files, GET /api/file
+	PermFilesWrite     Permission = "files:write"     // PUT/DELETE /api/file, POST /api/directory
+	PermAuditRead      Permission = "audit:read"      // GET /api/audit
+	PermMetricsRead    Permission = "metrics:read"    // GET /metrics
+	PermAlertsRead     Permission = "alerts:read"     // GET /api/alerts
+	PermAlertsSilence  Permission = "alerts:silence"  // POST/DELETE /api/alerts/silences
+)
+
+// permissions — матрица прав: каждая роль включает права предыдущей
+var permissions = map[Role][]Permission{
+	RoleViewer: {
+		PermHealthRead,
+		PermLogsRead,
+		PermServiceRead,
+		PermMetricsRead,
+		PermAlertsRead,
+	},
+	RoleOperator: {
+		PermHealthRead,
+		PermLogsRead,
+		PermServiceRead,
+		PermMetricsRead,
+		PermAlertsRead,
+		PermServiceControl,
+		PermFilesRead,
+		PermAlertsSilence,
+	},
+	RoleAdmin: {
+		PermHealthRead,
+		PermLogsRead,
+		PermServiceRead,
+		PermMetricsRead,
+		PermAlertsRead,
+		PermServiceControl,
+		PermFilesRead,
+		PermAlertsSilence,
+		PermFilesWrite,
+		PermAuditRead,
+	},
+}
+
+// ParseRole проверяет имя роли
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if _, ok := permissions[role]; !ok {
+		return "", fmt.Errorf("unknown role %q (expected viewer, operator or admin)", name)
+	}
+	return role, nil
+}
+
+// Can сообщает, есть ли у роли право
+func (r Role) Can(perm Permission) bool {
+	for _, p := range permissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions возвращает отсортированный список прав роли
+func (r Role) Permissions() []Permission {
+	perms := append([]Permission(nil), permissions[r]...)
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	return perms
+}
+
+// AccessError — структурированный ответ 401/403
+type AccessError struct {
+	Error      string     `json:"error"`
+	Message    string     `json:"message"`
+	Permission Permission `json:"permission"`
+	Role       Role       `json:"role,omitempty"`
+}
+
+// Allow проверяет право текущего пользователя. Если права нет — пишет 401/403 и возвращает false
+func Allow(w http.ResponseWriter, r *http.Request, perm Permission) bool {
+	id, ok := FromContext(r.Context())
+	if !ok {
+		writeAccessError(w, http.StatusUnauthorized, AccessError{
+			Error:      "unauthorized",
+			Message:    "authentication required",
+			Permission: perm,
+		})
+		return false
+	}
+
+	if !id.Role.Can(perm) {
+		writeAccessError(w, http.StatusForbidden, AccessError{
+			Error:      "forbidden",
+			Message:    fmt.Sprintf("role %q does not have permission %q", id.Role, perm),
+			Permission: perm,
+			Role:       id.Role,
+		})
+		return false
+	}
+
+	return true
+}
+
+func writeAccessError(w http.ResponseWriter, status int, body AccessError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+)
+
+// minRole — младшая роль, которой доступно право; старшие роли его наследуют
+var minRole = map[Permission]Role{
+	PermHealthRead:     RoleViewer,
+	PermLogsRead:       RoleViewer,
+	PermServiceRead:    RoleViewer,
+	PermMetricsRead:    RoleViewer,
+	PermAlertsRead:     RoleViewer,
+	PermServiceControl: RoleOperator,
+	PermFilesRead:      RoleOperator,
+	PermAlertsSilence:  RoleOperator,
+	PermFilesWrite:     RoleAdmin,
+	PermAuditRead:      RoleAdmin,
+}
+
+var roleRank = map[Role]int{RoleViewer: 1, RoleOperator: 2, RoleAdmin: 3}
+
+func TestRolePermissions(t *testing.T) {
+	for role, rank := range roleRank {
+		var want []Permission
+		for perm, min := range minRole {
+			allowed := rank >= roleRank[min]
+			if got := role.Can(perm); got != allowed {
+				t.Errorf("%s.Can(%s) = %v, want %v", role, perm, got, allowed)
+			}
+			if allowed {
+				want = append(want, perm)
+			}
+		}
+
+		// Permissions (отдаётся в /api/auth/me) совпадает с Can и отсортирован
+		sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+		got := role.Permissions()
+		if len(got) != len(want) {
+			t.Fatalf("%s.Permissions() = %v, want %v", role, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s.Permissions() = %v, want %v", role, got, want)
+				break
+			}
+		}
+	}
+
+	// Новое право без строки в minRole не должно проскочить мимо теста
+	if n := len(permissions[RoleAdmin]); n != len(minRole) {
+		t.Errorf("admin has %d permissions, the test knows %d", n, len(minRole))
+	}
+
+	for perm := range minRole {
+		if Role("root").Can(perm) || Role("").Can(perm) {
+			t.Errorf("unknown role can %s", perm)
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	for _, name := range []string{"viewer", "operator", "admin"} {
+		if role, err := ParseRole(name); err != nil || string(role) != name {
+			t.Errorf("ParseRole(%q) = %q, %v", name, role, err)
+		}
+	}
+	for _, name := range []string{"", "Admin", "root"} {
+		if _, err := ParseRole(name); err == nil {
+			t.Errorf("ParseRole(%q) accepted", name)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity *Identity
+		perm     Permission
+		code     int
+		body     map[string]string
+	}{
+		{
+			name: "no identity",
+			perm: PermLogsRead,
+			code: http.StatusUnauthorized,
+			body: map[string]string{
+				"error":      "unauthorized",
+				"message":    "authentication required",
+				"permission": "logs:read",
+			},
+		},
+		{
+			name:     "missing permission",
+			identity: &Identity{Username: "bob", Role: RoleViewer, Method: MethodSession},
+			perm:     PermFilesWrite,
+			code:     http.StatusForbidden,
+			body: map[string]string{
+				"error":      "forbidden",
+				"message":    `role "viewer" does not have permission "files:write"`,
+				"permission": "files:write",
+				"role":       "viewer",
+			},
+		},
+		{
+			name:     "allowed",
+			identity: &Identity{Username: "alice", Role: RoleAdmin, Method: MethodToken},
+			perm:     PermFilesWrite,
+			code:     http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/x", nil)
+			if tt.identity != nil {
+				r = r.WithContext(WithIdentity(r.Context(), *tt.identity))
+			}
+			w := httptest.NewRecorder()
+
+			ok := Allow(w, r, tt.perm)
+			if ok != (tt.code == http.StatusOK) || w.Code != tt.code {
+				t.Fatalf("Allow = %v, code %d; want code %d", ok, w.Code, tt.code)
+			}
+			if tt.body == nil {
+				if w.Body.Len() != 0 {
+					t.Errorf("allowed request got body %s", w.Body)
+				}
+				return
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			var body map[string]string
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body) != len(tt.body) {
+				t.Errorf("body = %v, want %v", body, tt.body)
+			}
+			for k, v := range tt.body {
+				if body[k] != v {
+					t.Errorf("%s = %q, want %q", k, body[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Session — cookie-сессия веб-интерфейса (хранится в памяти)
+type Session struct {
+	ID        string
+	Username  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Sessions — in-memory хранилище сессий. После рестарта нужно войти заново
+type Sessions struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+func NewSessions(ttl time.Duration) *Sessions {
+	return &Sessions{
+		ttl:      ttl,
+		sessions: make(map[string]Session),
+	}
+}
+
+// Create открывает новую сессию для пользователя
+func (s *Sessions) Create(username string) (Session, error) {
+	id, err := randomString(32)
+	if err != nil {
+		return Session{}, err
+	}
+
+	now := time.Now()
+	session := Session{
+		ID:        id,
+		Username:  username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(now)
+	s.sessions[id] = session
+
+	return session, nil
+}
+
+// Get возвращает действующую сессию
+func (s *Sessions) Get(id string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	if time.Now().After(session.ExpiresAt) {
+		delete(s.sessions, id)
+		return Session{}, false
+	}
+	return session, true
+}
+
+// Delete закрывает сессию
+func (s *Sessions) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// pruneLocked удаляет истёкшие сессии (вызывать под s.mu)
+func (s *Sessions) pruneLocked(now time.Time) {
+	for id, session := range s.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TokenPrefix — префикс API токенов, чтобы их было легко узнать в конфигах и логах
+const TokenPrefix = "pcd_"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrTokenNotFound      = errors.New("token not found")
+)
+
+// User — локальный пользователь дашборда
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"` // bcrypt
+	Role         Role   `json:"role"`
+}
+
+// APIToken — bearer токен для скриптов. Хранится только SHA-256 от токена
+type APIToken struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Username  string     `json:"username"`
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// storeFile — формат файла пользователей
+type storeFile struct {
+	Users  []User     `json:"users"`
+	Tokens []APIToken `json:"tokens"`
+}
+
+// Store — файловое хранилище пользователей и API токенов
+type Store struct {
+	path string
+
+	mu     sync.RWMutex
+	users  map[string]User
+	tokens map[string]APIToken // по хэшу токена
+}
+
+// dummyHash используется для сравнения, когда пользователь не найден,
+// чтобы время ответа не выдавало существование логина
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("picoclaw-dashboard"), bcrypt.DefaultCost)
+
+// OpenStore загружает хранилище из файла. Отсутствующий файл — пустое хранилище
+func OpenStore(path string) (*Store, error) {
+	s := &Store{
+		path:   path,
+		users:  make(map[string]User),
+		tokens: make(map[string]APIToken),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read users file: %w", err)
+	}
+
+	var f storeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse users file %s: %w", path, err)
+	}
+	for _, u := range f.Users {
+		// Пользователи, созданные до появления ролей, имели полный доступ
+		if u.Role == "" {
+			u.Role = RoleAdmin
+		}
+		if _, err := ParseRole(string(u.Role)); err != nil {
+			return nil, fmt.Errorf("users file %s: user %s: %w", path, u.Username, err)
+		}
+		s.users[u.Username] = u
+	}
+	for _, t := range f.Tokens {
+		s.tokens[t.Hash] = t
+	}
+
+	return s, nil
+}
+
+// UserCount возвращает количество пользователей
+func (s *Store) UserCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.users)
+}
+
+// Authenticate проверяет логин и пароль
+func (s *Store) Authenticate(username, password string) (User, error) {
+	s.mu.RLock()
+	user, ok := s.users[username]
+	s.mu.RUnlock()
+
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return User{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return User{}, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// User возвращает пользователя по имени
+func (s *Store) User(username string) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[username]
+	return u, ok
+}
+
+// SetPassword создаёт пользователя или меняет ему пароль и роль.
+// Пустая роль сохраняет текущую; новому пользователю достаётся admin,
+// если он первый, и viewer — иначе
+func (s *Store) SetPassword(username, password string, role Role) error {
+	if username == "" {
+		return errors.New("username is required")
+	}
+	if len(password) < 8 {
+		return errors.New("password must be at least 8 characters")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.users[username]
+	user.Username = username
+	user.PasswordHash = string(hash)
+	switch {
+	case role != "":
+		user.Role = role
+	case !exists && len(s.users) == 0:
+		user.Role = RoleAdmin
+	case !exists:
+		user.Role = RoleViewer
+	}
+	s.users[username] = user
+
+	return s.saveLocked()
+}
+
+// LookupToken находит действующий токен по его открытому значению
+func (s *Store) LookupToken(raw string) (APIToken, bool) {
+	hash := hashToken(raw)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tokens[hash]
+	if !ok {
+		return APIToken{}, false
+	}
+	if t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt) {
+		return APIToken{}, false
+	}
+	if _, ok := s.users[t.Username]; !ok {
+		return APIToken{}, false
+	}
+	return t, true
+}
+
+// CreateToken выпускает новый токен. Открытое значение возвращается только здесь
+func (s *Store) CreateToken(username, name string, ttl time.Duration) (string, APIToken, error) {
+	raw, err := randomString(32)
+	if err != nil {
+		return "", APIToken{}, err
+	}
+	raw = TokenPrefix + raw
+
+	id, err := randomString(8)
+	if err != nil {
+		return "", APIToken{}, err
+	}
+
+	token := APIToken{
+		ID:        id,
+		Name:      name,
+		Username:  username,
+		Hash:      hashToken(raw),
+		CreatedAt: time.Now().UTC(),
+	}
+	if ttl > 0 {
+		expires := token.CreatedAt.Add(ttl)
+		token.ExpiresAt = &expires
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[username]; !ok {
+		return "", APIToken{}, ErrUserNotFound
+	}
+	s.tokens[token.Hash] = token
+	if err := s.saveLocked(); err != nil {
+		delete(s.tokens, token.Hash)
+		return "", APIToken{}, err
+	}
+
+	return raw, token, nil
+}
+
+// Tokens возвращает токены пользователя
+func (s *Store) Tokens(username string) []APIToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []APIToken{}
+	for _, t := range s.tokens {
+		if t.Username == username {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// RevokeToken удаляет токен пользователя по ID
+func (s *Store) RevokeToken(username, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, t := range s.tokens {
+		if t.ID == id && t.Username == username {
+			delete(s.tokens, hash)
+			return s.saveLocked()
+		}
+	}
+	return ErrTokenNotFound
+}
+
+// saveLocked атомарно записывает файл (вызывать под s.mu)
+func (s *Store) saveLocked() error {
+	f := storeFile{
+		Users:  make([]User, 0, len(s.users)),
+		Tokens: make([]APIToken, 0, len(s.tokens)),
+	}
+	for _, u := range s.users {
+		f.Users = append(f.Users, u)
+	}
+	for _, t := range s.tokens {
+		f.Tokens = append(f.Tokens, t)
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("create users dir: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write users file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("write users file: %w", err)
+	}
+	return nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,768 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix — префикс переменных окружения, переопределяющих конфиг
+const EnvPrefix = "PICOCLAW_DASHBOARD_"
+
+// Config — полная конфигурация дашборда
+type Config struct {
+	Server    ServerConfig    `json:"server" yaml:"server" toml:"server"`
+	Service   ServiceConfig   `json:"service" yaml:"service" toml:"service"`
+	Logs      LogsConfig      `json:"logs" yaml:"logs" toml:"logs"`
+	Files     FilesConfig     `json:"files" yaml:"files" toml:"files"`
+	Metrics   MetricsConfig   `json:"metrics" yaml:"metrics" toml:"metrics"`
+	WebSocket WebSocketConfig `json:"websocket" yaml:"websocket" toml:"websocket"`
+	Auth      AuthConfig      `json:"auth" yaml:"auth" toml:"auth"`
+	Audit     AuditConfig     `json:"audit" yaml:"audit" toml:"audit"`
+	History   HistoryConfig   `json:"history" yaml:"history" toml:"history"`
+	Alerts    AlertsConfig    `json:"alerts" yaml:"alerts" toml:"alerts"`
+	Notify    NotifyConfig    `json:"notify" yaml:"notify" toml:"notify"`
+}
+
+// ServerConfig — параметры HTTP сервера
+type ServerConfig struct {
+	Host         string   `json:"host" yaml:"host" toml:"host"`
+	Port         int      `json:"port" yaml:"port" toml:"port"`
+	ReadTimeout  Duration `json:"read_timeout" yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout Duration `json:"write_timeout" yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  Duration `json:"idle_timeout" yaml:"idle_timeout" toml:"idle_timeout"`
+}
+
+// ServiceConfig — управляемые systemd сервисы
+type ServiceConfig struct {
+	Unit    string   `json:"unit" yaml:"unit" toml:"unit"`          // основной сервис (карточка на дашборде, алерты)
+	Units   []string `json:"units" yaml:"units" toml:"units"`       // дополнительные сервисы, которыми можно управлять
+	Backend string   `json:"backend" yaml:"backend" toml:"backend"` // auto, dbus, exec, fake
+	// WatchInterval — опрос состояния юнитов; с D-Bus изменения приходят сразу, опрос — страховка
+	WatchInterval Duration `json:"watch_interval" yaml:"watch_interval" toml:"watch_interval"`
+	CacheTTL      Duration `json:"cache_ttl" yaml:"cache_ttl" toml:"cache_ttl"`
+}
+
+// LogsConfig — источник логов
+type LogsConfig struct {
+	Unit  string   `json:"unit" yaml:"unit" toml:"unit"`    // юнит по умолчанию
+	Units []string `json:"units" yaml:"units" toml:"units"` // дополнительные юниты, логи которых можно читать
+	// Последние записи, которые общий follower юнита хранит для новых подписчиков
+	Backlog int `json:"backlog" yaml:"backlog" toml:"backlog"`
+	// Очередь подписчика на поток логов; переполнивший её подписчик отключается
+	SubscriberBuffer int `json:"subscriber_buffer" yaml:"subscriber_buffer" toml:"subscriber_buffer"`
+	// Сколько времени запрос с фильтрами может читать журнал в поисках совпадений (0 — без предела)
+	QueryBudget Duration `json:"query_budget" yaml:"query_budget" toml:"query_budget"`
+
+	// Разбор сообщений: парсер по умолчанию, парсеры отдельных юнитов (юнит → имя)
+	// и пользовательские парсеры. Встроенные — BuiltinLogParsers
+	Parser  string            `json:"parser" yaml:"parser" toml:"parser"`
+	Sources map[string]string `json:"sources" yaml:"sources" toml:"sources"`
+	Parsers []LogParser       `json:"parsers" yaml:"parsers" toml:"parsers"`
+	// Регулярные выражения для полей записей: именованные группы — имена полей
+	Fields []string `json:"fields" yaml:"fields" toml:"fields"`
+}
+
+// LogParser — пользовательский парсер строк лога: регулярное выражение с именованными
+// группами message (или msg), level и time
+type LogParser struct {
+	Name       string `json:"name" yaml:"name" toml:"name"`
+	Pattern    string `json:"pattern" yaml:"pattern" toml:"pattern"`
+	TimeFormat string `json:"time_format" yaml:"time_format" toml:"time_format"` // layout Go для группы time; пусто — RFC3339 или unix-время
+}
+
+// BuiltinLogParsers — встроенные парсеры pkg/logs
+var BuiltinLogParsers = []string{"picoclaw", "json", "logfmt", "raw"}
+
+// FilesConfig — файловый менеджер
+type FilesConfig struct {
+	BaseDir string `json:"base_dir" yaml:"base_dir" toml:"base_dir"`
+}
+
+// MetricsConfig — рассылка метрик
+type MetricsConfig struct {
+	BroadcastInterval Duration `json:"broadcast_interval" yaml:"broadcast_interval" toml:"broadcast_interval"`
+}
+
+// WebSocketConfig — буферы WebSocket хаба
+type WebSocketConfig struct {
+	BroadcastBuffer int `json:"broadcast_buffer" yaml:"broadcast_buffer" toml:"broadcast_buffer"`
+	SendBuffer      int `json:"send_buffer" yaml:"send_buffer" toml:"send_buffer"`
+}
+
+// AuthConfig — встроенная авторизация (по умолчанию выключена)
+type AuthConfig struct {
+	Enabled      bool     `json:"enabled" yaml:"enabled" toml:"enabled"`
+	UsersFile    string   `json:"users_file" yaml:"users_file" toml:"users_file"`
+	SessionTTL   Duration `json:"session_ttl" yaml:"session_ttl" toml:"session_ttl"`
+	CookieSecure bool     `json:"cookie_secure" yaml:"cookie_secure" toml:"cookie_secure"`
+	// AnonymousRole — роль всех запросов, когда авторизация выключена
+	AnonymousRole string `json:"anonymous_role" yaml:"anonymous_role" toml:"anonymous_role"`
+}
+
+// AuditConfig — журнал аудита изменяющих действий
+type AuditConfig struct {
+	Enabled   bool   `json:"enabled" yaml:"enabled" toml:"enabled"`
+	File      string `json:"file" yaml:"file" toml:"file"`
+	MaxSizeMB int    `json:"max_size_mb" yaml:"max_size_mb" toml:"max_size_mb"` // размер файла до ротации
+	MaxFiles  int    `json:"max_files" yaml:"max_files" toml:"max_files"`       // сколько архивов хранить
+}
+
+// HistoryConfig — история метрик в памяти и её агрегаты
+type HistoryConfig struct {
+	Resolution      Duration `json:"resolution" yaml:"resolution" toml:"resolution"`          // шаг сырых замеров
+	RawRetention    Duration `json:"raw_retention" yaml:"raw_retention" toml:"raw_retention"` // сколько хранить сырые замеры
+	Retention1m     Duration `json:"retention_1m" yaml:"retention_1m" toml:"retention_1m"`
+	Retention5m     Duration `json:"retention_5m" yaml:"retention_5m" toml:"retention_5m"`
+	Retention1h     Duration `json:"retention_1h" yaml:"retention_1h" toml:"retention_1h"`
+	PersistFile     string   `json:"persist_file" yaml:"persist_file" toml:"persist_file"` // пусто — только в памяти
+	PersistInterval Duration `json:"persist_interval" yaml:"persist_interval" toml:"persist_interval"`
+}
+
+// AlertsConfig — правила алертов по метрикам хоста и состоянию сервиса
+type AlertsConfig struct {
+	Rules []AlertRule `json:"rules" yaml:"rules" toml:"rules"`
+}
+
+// AlertRule — правило вида "disk.used_percent > 85", срабатывает после For
+type AlertRule struct {
+	Name        string   `json:"name" yaml:"name" toml:"name"`
+	Expr        string   `json:"expr" yaml:"expr" toml:"expr"`
+	For         Duration `json:"for" yaml:"for" toml:"for"`
+	Severity    string   `json:"severity" yaml:"severity" toml:"severity"` // по умолчанию warning
+	Description string   `json:"description" yaml:"description" toml:"description"`
+}
+
+// NotifyConfig — каналы уведомлений и события, которые в них отправляются
+type NotifyConfig struct {
+	Channels           []NotifyChannel `json:"channels" yaml:"channels" toml:"channels"`
+	ServiceTransitions bool            `json:"service_transitions" yaml:"service_transitions" toml:"service_transitions"` // смены статуса сервиса
+	LogErrors          bool            `json:"log_errors" yaml:"log_errors" toml:"log_errors"`                            // записи ERROR и FATAL из logs.unit
+	MaxAttempts        int             `json:"max_attempts" yaml:"max_attempts" toml:"max_attempts"`                      // попыток отправки, включая первую
+	InitialBackoff     Duration        `json:"initial_backoff" yaml:"initial_backoff" toml:"initial_backoff"`
+	MaxBackoff         Duration        `json:"max_backoff" yaml:"max_backoff" toml:"max_backoff"`
+}
+
+// NotifyChannel — один канал уведомлений. Набор полей зависит от Type
+type NotifyChannel struct {
+	Name       string   `json:"name" yaml:"name" toml:"name"`
+	Type       string   `json:"type" yaml:"type" toml:"type"`                   // webhook, telegram, smtp
+	Events     []string `json:"events" yaml:"events" toml:"events"`             // alert, service, log; пусто — все
+	Template   string   `json:"template" yaml:"template" toml:"template"`       // text/template; пусто — шаблон по умолчанию
+	RateLimit  int      `json:"rate_limit" yaml:"rate_limit" toml:"rate_limit"` // сообщений за rate_window; 0 — без ограничения
+	RateWindow Duration `json:"rate_window" yaml:"rate_window" toml:"rate_window"`
+
+	// webhook
+	URL     string            `json:"url" yaml:"url" toml:"url"`
+	Headers map[string]string `json:"headers" yaml:"headers" toml:"headers"`
+
+	// telegram
+	BotToken string `json:"bot_token" yaml:"bot_token" toml:"bot_token"`
+	ChatID   string `json:"chat_id" yaml:"chat_id" toml:"chat_id"`
+	APIURL   string `json:"api_url" yaml:"api_url" toml:"api_url"` // по умолчанию https://api.telegram.org
+
+	// smtp
+	Host     string   `json:"host" yaml:"host" toml:"host"`
+	Port     int      `json:"port" yaml:"port" toml:"port"`
+	Username string   `json:"username" yaml:"username" toml:"username"`
+	Password string   `json:"password" yaml:"password" toml:"password"`
+	From     string   `json:"from" yaml:"from" toml:"from"`
+	To       []string `json:"to" yaml:"to" toml:"to"`
+}
+
+// Duration — time.Duration, которая читается из строк вида "5s" или "1m30s"
+type Duration time.Duration
+
+// Std возвращает значение как time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalText реализует encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText реализует encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// ParseDuration — time.ParseDuration с поддержкой дней ("7d"); отрицательные значения — ошибка.
+// Так читаются длительности и периоды в параметрах API
+func ParseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseInt(days, 10, 64)
+		if err != nil || n < 0 || n > math.MaxInt64/int64(24*time.Hour) {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+// ParseTime понимает RFC3339, unix-время в секундах и относительное время
+// ("24h", "7d" — столько назад от now)
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	d, err := ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	return now.Add(-d), nil
+}
+
+// Default возвращает конфигурацию по умолчанию (совпадает с прежними захардкоженными значениями)
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:         8080,
+			ReadTimeout:  Duration(10 * time.Second),
+			WriteTimeout: Duration(10 * time.Second),
+			IdleTimeout:  Duration(60 * time.Second),
+		},
+		Service: ServiceConfig{
+			Unit:          "picoclaw",
+			Backend:       "auto",
+			CacheTTL:      Duration(5 * time.Second),
+			WatchInterval: Duration(2 * time.Second),
+		},
+		Logs: LogsConfig{
+			Unit:             "picoclaw",
+			Backlog:          100,
+			SubscriberBuffer: 256,
+			QueryBudget:      Duration(5 * time.Second),
+			Parser:           "picoclaw",
+		},
+		Files: FilesConfig{
+			BaseDir: ".",
+		},
+		Metrics: MetricsConfig{
+			BroadcastInterval: Duration(5 * time.Second),
+		},
+		WebSocket: WebSocketConfig{
+			BroadcastBuffer: 256,
+			SendBuffer:      256,
+		},
+		Auth: AuthConfig{
+			UsersFile:     "/var/lib/picoclaw-dashboard/users.json",
+			SessionTTL:    Duration(24 * time.Hour),
+			AnonymousRole: "admin",
+		},
+		Audit: AuditConfig{
+			Enabled:   true,
+			File:      "/var/lib/picoclaw-dashboard/audit.jsonl",
+			MaxSizeMB: 10,
+			MaxFiles:  5,
+		},
+		History: HistoryConfig{
+			Resolution:      Duration(5 * time.Second),
+			RawRetention:    Duration(time.Hour),
+			Retention1m:     Duration(24 * time.Hour),
+			Retention5m:     Duration(7 * 24 * time.Hour),
+			Retention1h:     Duration(30 * 24 * time.Hour),
+			PersistInterval: Duration(time.Minute),
+		},
+		Notify: NotifyConfig{
+			ServiceTransitions: true,
+			LogErrors:          true,
+			MaxAttempts:        5,
+			InitialBackoff:     Duration(time.Second),
+			MaxBackoff:         Duration(time.Minute),
+		},
+	}
+}
+
+// Addr возвращает адрес для http.Server
+func (c *Config) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
+}
+
+// Load собирает конфигурацию: значения по умолчанию → файл → переменные окружения → флаги
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("picoclaw-dashboard", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to config file (.yaml, .yml, .json or .toml)")
+	host := fs.String("host", "", "listen host")
+	port := fs.Int("port", 0, "listen port")
+	unit := fs.String("unit", "", "systemd unit to control")
+	logsUnit := fs.String("logs-unit", "", "systemd unit to read logs from")
+	baseDir := fs.String("base-dir", "", "root directory of the file manager")
+	interval := fs.Duration("metrics-interval", 0, "metrics broadcast interval")
+	authEnabled := fs.Bool("auth", false, "enable built-in authentication")
+	usersFile := fs.String("users-file", "", "path to the users and API tokens file")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Файл конфигурации: флаг имеет приоритет над переменной окружения
+	path := os.Getenv(EnvPrefix + "CONFIG")
+	if *configPath != "" {
+		path = *configPath
+	}
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	// Применяем только явно переданные флаги
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "host":
+			cfg.Server.Host = *host
+		case "port":
+			cfg.Server.Port = *port
+		case "unit":
+			cfg.Service.Unit = *unit
+		case "logs-unit":
+			cfg.Logs.Unit = *logsUnit
+		case "base-dir":
+			cfg.Files.BaseDir = *baseDir
+		case "metrics-interval":
+			cfg.Metrics.BroadcastInterval = Duration(*interval)
+		case "auth":
+			cfg.Auth.Enabled = *authEnabled
+		case "users-file":
+			cfg.Auth.UsersFile = *usersFile
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile читает конфиг, формат определяется по расширению
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parse %s: unknown key %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("unsupported config format: %s", path)
+	}
+
+	return nil
+}
+
+// applyEnv переопределяет значения из переменных окружения PICOCLAW_DASHBOARD_*
+func applyEnv(cfg *Config) error {
+	strVars := map[string]*string{
+		"HOST":                 &cfg.Server.Host,
+		"SERVICE_UNIT":         &cfg.Service.Unit,
+		"SERVICE_BACKEND":      &cfg.Service.Backend,
+		"LOGS_UNIT":            &cfg.Logs.Unit,
+		"LOGS_PARSER":          &cfg.Logs.Parser,
+		"FILES_BASE_DIR":       &cfg.Files.BaseDir,
+		"AUTH_USERS_FILE":      &cfg.Auth.UsersFile,
+		"AUTH_ANONYMOUS_ROLE":  &cfg.Auth.AnonymousRole,
+		"AUDIT_FILE":           &cfg.Audit.File,
+		"HISTORY_PERSIST_FILE": &cfg.History.PersistFile,
+	}
+	for name, dst := range strVars {
+		if v, ok := os.LookupEnv(EnvPrefix + name); ok {
+			*dst = v
+		}
+	}
+
+	// Списки через запятую
+	listVars := map[string]*[]string{
+		"SERVICE_UNITS": &cfg.Service.Units,
+		"LOGS_UNITS":    &cfg.Logs.Units,
+	}
+	for name, dst := range listVars {
+		if v, ok := os.LookupEnv(EnvPrefix + name); ok {
+			*dst = nil
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*dst = append(*dst, item)
+				}
+			}
+		}
+	}
+
+	intVars := map[string]*int{
+		"PORT":                       &cfg.Server.Port,
+		"WEBSOCKET_BROADCAST_BUFFER": &cfg.WebSocket.BroadcastBuffer,
+		"WEBSOCKET_SEND_BUFFER":      &cfg.WebSocket.SendBuffer,
+		"LOGS_BACKLOG":               &cfg.Logs.Backlog,
+		"LOGS_SUBSCRIBER_BUFFER":     &cfg.Logs.SubscriberBuffer,
+	}
+	for name, dst := range intVars {
+		if v, ok := os.LookupEnv(EnvPrefix + name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s%s: %w", EnvPrefix, name, err)
+			}
+			*dst = n
+		}
+	}
+
+	boolVars := map[string]*bool{
+		"AUTH_ENABLED":       &cfg.Auth.Enabled,
+		"AUTH_COOKIE_SECURE": &cfg.Auth.CookieSecure,
+		"AUDIT_ENABLED":      &cfg.Audit.Enabled,
+	}
+	for name, dst := range boolVars {
+		if v, ok := os.LookupEnv(EnvPrefix + name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s%s: %w", EnvPrefix, name, err)
+			}
+			*dst = b
+		}
+	}
+
+	durVars := map[string]*Duration{
+		"READ_TIMEOUT":           &cfg.Server.ReadTimeout,
+		"WRITE_TIMEOUT":          &cfg.Server.WriteTimeout,
+		"IDLE_TIMEOUT":           &cfg.Server.IdleTimeout,
+		"SERVICE_CACHE_TTL":      &cfg.Service.CacheTTL,
+		"SERVICE_WATCH_INTERVAL": &cfg.Service.WatchInterval,
+		"METRICS_INTERVAL":       &cfg.Metrics.BroadcastInterval,
+		"AUTH_SESSION_TTL":       &cfg.Auth.SessionTTL,
+		"HISTORY_RESOLUTION":     &cfg.History.Resolution,
+		"LOGS_QUERY_BUDGET":      &cfg.Logs.QueryBudget,
+	}
+	for name, dst := range durVars {
+		if v, ok := os.LookupEnv(EnvPrefix + name); ok {
+			if err := dst.UnmarshalText([]byte(v)); err != nil {
+				return fmt.Errorf("%s%s: %w", EnvPrefix, name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// unitPattern — допустимые имена systemd юнитов
+var unitPattern = regexp.MustCompile(`^[A-Za-z0-9:_.@\\-]+$`)
+
+// AllowedUnits возвращает основной сервис и дополнительные без повторов
+func (s ServiceConfig) AllowedUnits() []string {
+	return mergeUnits(s.Unit, s.Units)
+}
+
+// AllowedUnits возвращает юнит по умолчанию и дополнительные без повторов
+func (l LogsConfig) AllowedUnits() []string {
+	return mergeUnits(l.Unit, l.Units)
+}
+
+// mergeUnits — основной юнит, затем дополнительные без повторов
+func mergeUnits(main string, extra []string) []string {
+	units := []string{main}
+	seen := map[string]bool{main: true}
+	for _, unit := range extra {
+		if !seen[unit] {
+			seen[unit] = true
+			units = append(units, unit)
+		}
+	}
+	return units
+}
+
+// ValidUnitName проверяет имя systemd юнита
+func ValidUnitName(unit string) bool {
+	return unit != "" && len(unit) <= 256 && unitPattern.MatchString(unit)
+}
+
+// Validate проверяет конфигурацию целиком и возвращает все найденные ошибки
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port: must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		errs = append(errs, errors.New("server: timeouts must not be negative"))
+	}
+
+	if !ValidUnitName(c.Service.Unit) {
+		errs = append(errs, fmt.Errorf("service.unit: invalid unit name %q", c.Service.Unit))
+	}
+	for _, unit := range c.Service.Units {
+		if !ValidUnitName(unit) {
+			errs = append(errs, fmt.Errorf("service.units: invalid unit name %q", unit))
+		}
+	}
+	switch c.Service.Backend {
+	case "auto", "dbus", "exec", "fake":
+	default:
+		errs = append(errs, fmt.Errorf("service.backend: unknown backend %q (expected auto, dbus, exec or fake)", c.Service.Backend))
+	}
+	if c.Service.CacheTTL < 0 {
+		errs = append(errs, errors.New("service.cache_ttl: must not be negative"))
+	}
+	if c.Service.WatchInterval <= 0 {
+		errs = append(errs, errors.New("service.watch_interval: must be positive"))
+	}
+
+	if !ValidUnitName(c.Logs.Unit) {
+		errs = append(errs, fmt.Errorf("logs.unit: invalid unit name %q", c.Logs.Unit))
+	}
+	for _, unit := range c.Logs.Units {
+		if !ValidUnitName(unit) {
+			errs = append(errs, fmt.Errorf("logs.units: invalid unit name %q", unit))
+		}
+	}
+	if c.Logs.Backlog < 0 {
+		errs = append(errs, errors.New("logs.backlog: must not be negative"))
+	}
+	if c.Logs.QueryBudget < 0 {
+		errs = append(errs, errors.New("logs.query_budget: must not be negative"))
+	}
+	if c.Logs.SubscriberBuffer < 1 {
+		errs = append(errs, errors.New("logs.subscriber_buffer: must be at least 1"))
+	}
+	errs = append(errs, c.Logs.validateParsers()...)
+
+	if c.Files.BaseDir == "" {
+		errs = append(errs, errors.New("files.base_dir: must not be empty"))
+	} else if info, err := os.Stat(c.Files.BaseDir); err != nil {
+		errs = append(errs, fmt.Errorf("files.base_dir: %w", err))
+	} else if !info.IsDir() {
+		errs = append(errs, fmt.Errorf("files.base_dir: %s is not a directory", c.Files.BaseDir))
+	}
+
+	if c.Metrics.BroadcastInterval <= 0 {
+		errs = append(errs, errors.New("metrics.broadcast_interval: must be positive"))
+	}
+
+	if c.WebSocket.BroadcastBuffer < 1 {
+		errs = append(errs, errors.New("websocket.broadcast_buffer: must be at least 1"))
+	}
+	if c.WebSocket.SendBuffer < 1 {
+		errs = append(errs, errors.New("websocket.send_buffer: must be at least 1"))
+	}
+
+	if c.Auth.Enabled {
+		if c.Auth.UsersFile == "" {
+			errs = append(errs, errors.New("auth.users_file: required when auth is enabled"))
+		} else if within(c.Files.BaseDir, c.Auth.UsersFile) {
+			errs = append(errs, errors.New("auth.users_file: must not be inside files.base_dir"))
+		}
+		if c.Auth.SessionTTL <= 0 {
+			errs = append(errs, errors.New("auth.session_ttl: must be positive"))
+		}
+	}
+
+	if c.Audit.Enabled {
+		if c.Audit.File == "" {
+			errs = append(errs, errors.New("audit.file: required when audit is enabled"))
+		} else if within(c.Files.BaseDir, c.Audit.File) {
+			errs = append(errs, errors.New("audit.file: must not be inside files.base_dir"))
+		}
+		if c.Audit.MaxSizeMB < 1 {
+			errs = append(errs, errors.New("audit.max_size_mb: must be at least 1"))
+		}
+		if c.Audit.MaxFiles < 0 {
+			errs = append(errs, errors.New("audit.max_files: must not be negative"))
+		}
+	}
+
+	h := c.History
+	if h.Resolution <= 0 {
+		errs = append(errs, errors.New("history.resolution: must be positive"))
+	} else if h.RawRetention < h.Resolution {
+		errs = append(errs, errors.New("history.raw_retention: must be at least history.resolution"))
+	}
+	if h.Retention1m < Duration(time.Minute) || h.Retention5m < Duration(5*time.Minute) || h.Retention1h < Duration(time.Hour) {
+		errs = append(errs, errors.New("history: rollup retention must cover at least one interval (1m, 5m, 1h)"))
+	}
+	if h.PersistFile != "" && h.PersistInterval <= 0 {
+		errs = append(errs, errors.New("history.persist_interval: must be positive"))
+	}
+
+	errs = append(errs, c.Notify.validate()...)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validateParsers проверяет пользовательские парсеры, ссылки на них и правила полей
+func (l LogsConfig) validateParsers() []error {
+	var errs []error
+
+	known := make(map[string]bool)
+	for _, name := range BuiltinLogParsers {
+		known[name] = true
+	}
+	for i, p := range l.Parsers {
+		prefix := fmt.Sprintf("logs.parsers[%d]", i)
+		if p.Name != "" {
+			prefix = "logs.parsers." + p.Name
+		}
+		switch {
+		case p.Name == "":
+			errs = append(errs, fmt.Errorf("%s: name is required", prefix))
+		case known[p.Name]:
+			errs = append(errs, fmt.Errorf("%s: name is already taken", prefix))
+		}
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid pattern: %w", prefix, err))
+		} else if re.SubexpIndex("message") < 0 && re.SubexpIndex("msg") < 0 && re.SubexpIndex("level") < 0 {
+			errs = append(errs, fmt.Errorf("%s: pattern needs a named group message, msg or level", prefix))
+		}
+		if p.Name != "" {
+			known[p.Name] = true
+		}
+	}
+
+	if !known[l.Parser] {
+		errs = append(errs, fmt.Errorf("logs.parser: unknown parser %q", l.Parser))
+	}
+	for unit, name := range l.Sources {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("logs.sources.%s: unknown parser %q", unit, name))
+		}
+	}
+
+	for i, pattern := range l.Fields {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("logs.fields[%d]: invalid pattern: %w", i, err))
+			continue
+		}
+		named := false
+		for _, name := range re.SubexpNames() {
+			named = named || name != ""
+		}
+		if !named {
+			errs = append(errs, fmt.Errorf("logs.fields[%d]: pattern needs a named group", i))
+		}
+	}
+	return errs
+}
+
+// validate проверяет каналы уведомлений
+func (n NotifyConfig) validate() []error {
+	var errs []error
+
+	if n.MaxAttempts < 1 {
+		errs = append(errs, errors.New("notify.max_attempts: must be at least 1"))
+	}
+	if n.InitialBackoff <= 0 || n.MaxBackoff < n.InitialBackoff {
+		errs = append(errs, errors.New("notify: initial_backoff must be positive and not greater than max_backoff"))
+	}
+
+	seen := map[string]bool{}
+	for i, ch := range n.Channels {
+		prefix := fmt.Sprintf("notify.channels[%d]", i)
+		if ch.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name: required", prefix))
+		} else if seen[ch.Name] {
+			errs = append(errs, fmt.Errorf("%s.name: duplicate channel %q", prefix, ch.Name))
+		}
+		seen[ch.Name] = true
+
+		switch ch.Type {
+		case "webhook":
+			if ch.URL == "" {
+				errs = append(errs, fmt.Errorf("%s.url: required for webhook", prefix))
+			}
+		case "telegram":
+			if ch.BotToken == "" || ch.ChatID == "" {
+				errs = append(errs, fmt.Errorf("%s: bot_token and chat_id are required for telegram", prefix))
+			}
+		case "smtp":
+			if ch.Host == "" || ch.From == "" || len(ch.To) == 0 {
+				errs = append(errs, fmt.Errorf("%s: host, from and to are required for smtp", prefix))
+			}
+			if ch.Port < 0 || ch.Port > 65535 {
+				errs = append(errs, fmt.Errorf("%s.port: must be between 1 and 65535", prefix))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s.type: unknown type %q (expected webhook, telegram or smtp)", prefix, ch.Type))
+		}
+
+		for _, ev := range ch.Events {
+			if ev != "alert" && ev != "service" && ev != "log" {
+				errs = append(errs, fmt.Errorf("%s.events: unknown event %q (expected alert, service or log)", prefix, ev))
+			}
+		}
+		if ch.RateLimit < 0 || ch.RateWindow < 0 {
+			errs = append(errs, fmt.Errorf("%s: rate_limit and rate_window must not be negative", prefix))
+		}
+	}
+
+	return errs
+}
+
+// within сообщает, находится ли path внутри dir
+func within(dir, path string) bool {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"30s", 30 * time.Second, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"7d", 7 * 24 * time.Hour, true},
+		{"0d", 0, true},
+		{"-1h", 0, false},
+		{"-2d", 0, false},
+		{"1.5d", 0, false},
+		{"d", 0, false},
+		{"106751d", 106751 * 24 * time.Hour, true},
+		{"106752d", 0, false},
+		{"213504d", 0, false},
+		{"99999999999999999999d", 0, false},
+		{"week", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v, ok=%v", tt.value, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 2, 21, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2026-02-20T08:00:00Z", time.Date(2026, 2, 20, 8, 0, 0, 0, time.UTC)},
+		{"1771668000", time.Unix(1771668000, 0)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"2d", now.Add(-48 * time.Hour)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.value, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "-1h", "2026-02-20"} {
+		if _, err := ParseTime(value, now); err == nil {
+			t.Errorf("ParseTime(%q) accepted", value)
+		}
+	}
+}
+
+// writeConfig пишет файл конфигурации во временный каталог
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeConfig(t, "config.yaml", `
+server:
+  host: 10.0.0.1
+  port: 9000
+service:
+  unit: from-file
+logs:
+  backlog: 50
+`)
+	other := writeConfig(t, "other.yaml", "server:\n  port: 9300\n")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		port    int
+		unit    string
+		host    string
+		backlog int
+	}{
+		{name: "defaults", port: 8080, unit: "picoclaw", backlog: 100},
+		{name: "file", args: []string{"-config", file}, port: 9000, unit: "from-file", host: "10.0.0.1", backlog: 50},
+		{
+			name: "env over file",
+			env:  map[string]string{"CONFIG": file, "PORT": "9100", "SERVICE_UNIT": "from-env"},
+			port: 9100, unit: "from-env", host: "10.0.0.1", backlog: 50,
+		},
+		{
+			name: "flags over env",
+			env:  map[string]string{"PORT": "9100", "SERVICE_UNIT": "from-env"},
+			args: []string{"-config", file, "-port", "9200", "-unit", "from-flag"},
+			port: 9200, unit: "from-flag", host: "10.0.0.1", backlog: 50,
+		},
+		{
+			name: "config flag over config env",
+			env:  map[string]string{"CONFIG": file},
+			args: []string{"-config", other},
+			port: 9300, unit: "picoclaw", backlog: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(EnvPrefix+name, value)
+			}
+			cfg, err := Load(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Server.Port != tt.port || cfg.Service.Unit != tt.unit || cfg.Server.Host != tt.host || cfg.Logs.Backlog != tt.backlog {
+				t.Errorf("port %d, unit %q, host %q, backlog %d; want %d, %q, %q, %d",
+					cfg.Server.Port, cfg.Service.Unit, cfg.Server.Host, cfg.Logs.Backlog, tt.port, tt.unit, tt.host, tt.backlog)
+			}
+		})
+	}
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.yaml": "server:\n  port: 9000\n",
+		"config.json": `{"server": {"port": 9000}}`,
+		"config.toml": "[server]\nport = 9000\n",
+	} {
+		cfg, err := Load([]string{"-config", writeConfig(t, name, content)})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if cfg.Server.Port != 9000 {
+			t.Errorf("%s: port = %d, want 9000", name, cfg.Server.Port)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"unknown.yaml": "server:\n  prot: 9000\n",
+		"unknown.json": `{"server": {"prot": 9000}}`,
+		"unknown.toml": "[server]\nprot = 9000\n",
+		"config.ini":   "port=9000\n",
+	} {
+		if _, err := Load([]string{"-config", writeConfig(t, name, content)}); err == nil {
+			t.Errorf("%s accepted", name)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Run("bad env", func(t *testing.T) {
+		t.Setenv(EnvPrefix+"PORT", "http")
+		if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), EnvPrefix+"PORT") {
+			t.Errorf("err = %v, want one naming %sPORT", err, EnvPrefix)
+		}
+	})
+	t.Run("invalid result", func(t *testing.T) {
+		if _, err := Load([]string{"-port", "70000"}); err == nil || !strings.Contains(err.Error(), "server.port") {
+			t.Errorf("err = %v, want a server.port error", err)
+		}
+	})
+	t.Run("unknown flag", func(t *testing.T) {
+		if _, err := Load([]string{"-prot", "1"}); err == nil {
+			t.Error("unknown flag accepted")
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *Config)
+		want   string
+	}{
+		{"port", func(c *Config) { c.Server.Port = 0 }, "server.port"},
+		{"timeouts", func(c *Config) { c.Server.ReadTimeout = -1 }, "server: timeouts"},
+		{"service unit", func(c *Config) { c.Service.Unit = "bad unit" }, "service.unit"},
+		{"service units", func(c *Config) { c.Service.Units = []string{"ok", "a/b"} }, "service.units"},
+		{"backend", func(c *Config) { c.Service.Backend = "systemctl" }, "service.backend"},
+		{"logs unit", func(c *Config) { c.Logs.Unit = "" }, "logs.unit"},
+		{"subscriber buffer", func(c *Config) { c.Logs.SubscriberBuffer = 0 }, "logs.subscriber_buffer"},
+		{"parser", func(c *Config) { c.Logs.Parser = "nope" }, "logs.parser"},
+		{"base dir", func(c *Config) { c.Files.BaseDir = filepath.Join(c.Files.BaseDir, "missing") }, "files.base_dir"},
+		{"interval", func(c *Config) { c.Metrics.BroadcastInterval = 0 }, "metrics.broadcast_interval"},
+		{"send buffer", func(c *Config) { c.WebSocket.SendBuffer = 0 }, "websocket.send_buffer"},
+		{"users file in base dir", func(c *Config) {
+			c.Auth.Enabled = true
+			c.Auth.UsersFile = filepath.Join(c.Files.BaseDir, "users.json")
+		}, "auth.users_file"},
+		{"session ttl", func(c *Config) {
+			c.Auth.Enabled = true
+			c.Auth.SessionTTL = 0
+		}, "auth.session_ttl"},
+		{"audit file in base dir", func(c *Config) { c.Audit.File = filepath.Join(c.Files.BaseDir, "audit.jsonl") }, "audit.file"},
+		{"raw retention", func(c *Config) { c.History.RawRetention = Duration(time.Second) }, "history.raw_retention"},
+		{"persist interval", func(c *Config) {
+			c.History.PersistFile = "/tmp/history.json"
+			c.History.PersistInterval = 0
+		}, "history.persist_interval"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Files.BaseDir = t.TempDir()
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("defaults: %v", err)
+			}
+			tt.mutate(cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one about %s", err, tt.want)
+			}
+		})
+	}
+
+	// Validate сообщает обо всех ошибках сразу
+	cfg := Default()
+	cfg.Server.Port = 0
+	cfg.Logs.Parser = "nope"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "server.port") || !strings.Contains(err.Error(), "logs.parser") {
+		t.Errorf("err = %v, want both errors", err)
+	}
+}
@@ -0,0 +1,307 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// Point — одна точка истории метрик. Для агрегатов — средние за интервал,
+// CPUMax — пик загрузки CPU, Samples — сколько исходных замеров вошло
+type Point struct {
+	Time       time.Time `json:"time"`
+	CPU        float64   `json:"cpu_percent"`
+	CPUMax     float64   `json:"cpu_max_percent"`
+	Memory     float64   `json:"memory_percent"`
+	MemoryUsed float64   `json:"memory_used_bytes"`
+	Disk       float64   `json:"disk_percent"`
+	DiskUsed   float64   `json:"disk_used_bytes"`
+	Samples    int       `json:"samples"`
+}
+
+// ring — кольцевой буфер фиксированной ёмкости
+type ring struct {
+	Points []Point `json:"points"`
+	Head   int     `json:"head"` // индекс следующей записи
+	Count  int     `json:"count"`
+}
+
+func newRing(capacity int) *ring {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &ring{Points: make([]Point, capacity)}
+}
+
+func (r *ring) add(p Point) {
+	r.Points[r.Head] = p
+	r.Head = (r.Head + 1) % len(r.Points)
+	if r.Count < len(r.Points) {
+		r.Count++
+	}
+}
+
+// rangeOf возвращает точки в [from, to] от старых к новым
+func (r *ring) rangeOf(from, to time.Time) []Point {
+	var result []Point
+	start := (r.Head - r.Count + len(r.Points)) % len(r.Points)
+	for i := 0; i < r.Count; i++ {
+		p := r.Points[(start+i)%len(r.Points)]
+		if p.Time.Before(from) || p.Time.After(to) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// accumulator собирает замеры текущего интервала агрегата
+type accumulator struct {
+	Start time.Time `json:"start"`
+	Sum   Point     `json:"sum"`
+}
+
+func (a *accumulator) add(p Point) {
+	a.Sum.CPU += p.CPU * float64(p.Samples)
+	a.Sum.Memory += p.Memory * float64(p.Samples)
+	a.Sum.MemoryUsed += p.MemoryUsed * float64(p.Samples)
+	a.Sum.Disk += p.Disk * float64(p.Samples)
+	a.Sum.DiskUsed += p.DiskUsed * float64(p.Samples)
+	if p.CPUMax > a.Sum.CPUMax {
+		a.Sum.CPUMax = p.CPUMax
+	}
+	a.Sum.Samples += p.Samples
+}
+
+func (a *accumulator) point() Point {
+	n := float64(a.Sum.Samples)
+	return Point{
+		Time:       a.Start,
+		CPU:        a.Sum.CPU / n,
+		CPUMax:     a.Sum.CPUMax,
+		Memory:     a.Sum.Memory / n,
+		MemoryUsed: a.Sum.MemoryUsed / n,
+		Disk:       a.Sum.Disk / n,
+		DiskUsed:   a.Sum.DiskUsed / n,
+		Samples:    a.Sum.Samples,
+	}
+}
+
+// level — уровень истории: сырые замеры или агрегат с шагом Step
+type level struct {
+	Name      string        `json:"name"`
+	Step      time.Duration `json:"step"`
+	Retention time.Duration `json:"retention"`
+	Ring      *ring         `json:"ring"`
+	Acc       accumulator   `json:"acc"` // не используется для сырого уровня
+}
+
+// Store — история метрик: сырые замеры и агрегаты 1m, 5m, 1h
+type Store struct {
+	persistFile string
+
+	mu     sync.RWMutex
+	levels []*level // levels[0] — сырые замеры
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewStore создаёт хранилище и подгружает сохранённую историю, если она есть
+func NewStore(cfg *config.Config) (*Store, error) {
+	h := cfg.History
+	s := &Store{persistFile: h.PersistFile, now: time.Now}
+
+	specs := []struct {
+		name      string
+		step      time.Duration
+		retention time.Duration
+	}{
+		{"raw", h.Resolution.Std(), h.RawRetention.Std()},
+		{"1m", time.Minute, h.Retention1m.Std()},
+		{"5m", 5 * time.Minute, h.Retention5m.Std()},
+		{"1h", time.Hour, h.Retention1h.Std()},
+	}
+	for _, spec := range specs {
+		s.levels = append(s.levels, &level{
+			Name:      spec.name,
+			Step:      spec.step,
+			Retention: spec.retention,
+			Ring:      newRing(int(spec.retention / spec.step)),
+		})
+	}
+
+	if s.persistFile != "" {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Add записывает замер. Замеры чаще Resolution отбрасываются
+// (с допуском 10%, чтобы не терять тики с небольшим джиттером)
+func (s *Store) Add(p Point) {
+	if p.Samples == 0 {
+		p.Samples = 1
+	}
+	if p.CPUMax == 0 {
+		p.CPUMax = p.CPU
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw := s.levels[0]
+	if !s.last.IsZero() && p.Time.Sub(s.last) < raw.Step-raw.Step/10 {
+		return
+	}
+	s.last = p.Time
+	raw.Ring.add(p)
+
+	for _, l := range s.levels[1:] {
+		bucket := p.Time.Truncate(l.Step)
+		if !l.Acc.Start.Equal(bucket) {
+			if l.Acc.Sum.Samples > 0 {
+				l.Ring.add(l.Acc.point())
+			}
+			l.Acc = accumulator{Start: bucket}
+		}
+		l.Acc.add(p)
+	}
+}
+
+// Query возвращает точки в [from, to] с шагом не меньше step.
+// Берётся самый грубый уровень, который не грубее step и ещё хранит from
+func (s *Store) Query(from, to time.Time, step time.Duration) (points []Point, source string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l := s.pickLevel(from, step)
+
+	points = l.Ring.rangeOf(from, to)
+	// Незакрытый интервал агрегата тоже показываем, иначе последние минуты пропадут
+	if l.Acc.Sum.Samples > 0 {
+		if p := l.Acc.point(); !p.Time.Before(from) && !p.Time.After(to) {
+			points = append(points, p)
+		}
+	}
+
+	if step > l.Step {
+		points = downsample(points, step)
+	}
+	return points, l.Name
+}
+
+func (s *Store) pickLevel(from time.Time, step time.Duration) *level {
+	now := s.now()
+	var best *level
+	for _, l := range s.levels {
+		covers := !now.Add(-l.Retention).After(from)
+		if !covers {
+			continue
+		}
+		if l.Step <= step {
+			best = l // уровни упорядочены от мелкого к крупному
+		} else if best == nil {
+			return l // нужный шаг недоступен так далеко — берём самый подробный из покрывающих
+		}
+	}
+	if best == nil {
+		return s.levels[len(s.levels)-1] // from старше любого хранения — отдаём самый длинный уровень
+	}
+	return best
+}
+
+// downsample укрупняет точки до шага step
+func downsample(points []Point, step time.Duration) []Point {
+	var result []Point
+	var acc accumulator
+	for _, p := range points {
+		bucket := p.Time.Truncate(step)
+		if !acc.Start.Equal(bucket) {
+			if acc.Sum.Samples > 0 {
+				result = append(result, acc.point())
+			}
+			acc = accumulator{Start: bucket}
+		}
+		acc.add(p)
+	}
+	if acc.Sum.Samples > 0 {
+		result = append(result, acc.point())
+	}
+	return result
+}
+
+// Resolution возвращает шаг сырых замеров
+func (s *Store) Resolution() time.Duration {
+	return s.levels[0].Step
+}
+
+// snapshot — формат файла с сохранённой историей
+type snapshot struct {
+	Version int       `json:"version"`
+	Last    time.Time `json:"last"`
+	Levels  []*level  `json:"levels"`
+}
+
+// Save сохраняет историю на диск (если задан persist_file)
+func (s *Store) Save() error {
+	if s.persistFile == "" {
+		return nil
+	}
+
+	s.mu.RLock()
+	data, err := json.Marshal(snapshot{Version: 1, Last: s.last, Levels: s.levels})
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.persistFile), 0700); err != nil {
+		return fmt.Errorf("create history dir: %w", err)
+	}
+	tmp := s.persistFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	return os.Rename(tmp, s.persistFile)
+}
+
+// load восстанавливает историю. Уровни с изменённым шагом или ёмкостью пропускаются
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.persistFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read history: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("parse history %s: %w", s.persistFile, err)
+	}
+
+	for i, saved := range snap.Levels {
+		if i >= len(s.levels) || saved == nil || saved.Ring == nil {
+			continue
+		}
+		l := s.levels[i]
+		r := saved.Ring
+		if saved.Step != l.Step || len(r.Points) != len(l.Ring.Points) ||
+			r.Head < 0 || r.Head >= len(r.Points) || r.Count < 0 || r.Count > len(r.Points) {
+			continue
+		}
+		l.Ring = saved.Ring
+		l.Acc = saved.Acc
+	}
+	s.last = snap.Last
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+var base = time.Date(2026, 2, 21, 10, 0, 0, 0, time.UTC)
+
+// newTestStore создаёт хранилище с настройками по умолчанию и часами, стоящими на *now
+func newTestStore(t *testing.T, cfg *config.Config, now *time.Time) *Store {
+	t.Helper()
+	s, err := NewStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+// feed пишет n замеров с шагом Resolution начиная с from. CPU повторяет 0..11,
+// так что среднее за каждую минуту — 5.5, а пик — 11
+func feed(s *Store, from time.Time, n int) {
+	for i := 0; i < n; i++ {
+		s.Add(Point{
+			Time:   from.Add(time.Duration(i) * s.Resolution()),
+			CPU:    float64(i % 12),
+			Memory: 40,
+		})
+	}
+}
+
+func TestRingWraparound(t *testing.T) {
+	r := newRing(3)
+	for i := 0; i < 5; i++ {
+		r.add(Point{Time: base.Add(time.Duration(i) * time.Second), Samples: i})
+	}
+	if r.Count != 3 || r.Head != 2 {
+		t.Fatalf("count = %d, head = %d; want 3, 2", r.Count, r.Head)
+	}
+
+	points := r.rangeOf(base, base.Add(time.Minute))
+	if len(points) != 3 {
+		t.Fatalf("got %d points, want 3", len(points))
+	}
+	for i, p := range points {
+		if p.Samples != i+2 {
+			t.Errorf("points[%d] = #%d, want #%d (oldest first)", i, p.Samples, i+2)
+		}
+	}
+
+	if points := r.rangeOf(base.Add(3*time.Second), base.Add(3*time.Second)); len(points) != 1 || points[0].Samples != 3 {
+		t.Errorf("rangeOf a single second = %+v", points)
+	}
+	if len(newRing(0).Points) != 1 {
+		t.Error("ring of zero capacity")
+	}
+}
+
+func TestAddRollsUp(t *testing.T) {
+	now := base
+	s := newTestStore(t, config.Default(), &now)
+	raw, m1, m5 := s.levels[0], s.levels[1], s.levels[2]
+
+	feed(s, base, 12)
+	if m1.Ring.Count != 0 || m1.Acc.Sum.Samples != 12 {
+		t.Fatalf("1m before the minute ends: ring %d, acc %d samples", m1.Ring.Count, m1.Acc.Sum.Samples)
+	}
+
+	// Первый замер следующей минуты закрывает интервал
+	s.Add(Point{Time: base.Add(time.Minute), CPU: 100})
+	if m1.Ring.Count != 1 || m1.Acc.Sum.Samples != 1 {
+		t.Fatalf("1m after the minute ends: ring %d, acc %d samples", m1.Ring.Count, m1.Acc.Sum.Samples)
+	}
+	p := m1.Ring.Points[0]
+	if !p.Time.Equal(base) || p.CPU != 5.5 || p.CPUMax != 11 || p.Memory != 40 || p.Samples != 12 {
+		t.Errorf("1m point = %+v", p)
+	}
+	if m5.Ring.Count != 0 || m5.Acc.Sum.Samples != 13 || m5.Acc.Sum.CPUMax != 100 {
+		t.Errorf("5m: ring %d, acc %+v", m5.Ring.Count, m5.Acc)
+	}
+
+	// Замер раньше Resolution минус 10% отбрасывается, с небольшим джиттером — нет
+	s.Add(Point{Time: base.Add(time.Minute + 4*time.Second)})
+	s.Add(Point{Time: base.Add(time.Minute + 4600*time.Millisecond)})
+	if raw.Ring.Count != 14 {
+		t.Errorf("raw has %d points, want 14", raw.Ring.Count)
+	}
+}
+
+func TestAccumulatorWeightsSamples(t *testing.T) {
+	points := downsample([]Point{
+		{Time: base, CPU: 10, CPUMax: 20, Samples: 1},
+		{Time: base.Add(time.Minute), CPU: 40, CPUMax: 90, Samples: 3},
+		{Time: base.Add(5 * time.Minute), CPU: 1, CPUMax: 1, Samples: 1},
+	}, 5*time.Minute)
+
+	if len(points) != 2 {
+		t.Fatalf("got %d points, want 2", len(points))
+	}
+	if p := points[0]; p.CPU != 32.5 || p.CPUMax != 90 || p.Samples != 4 {
+		t.Errorf("first bucket = %+v, want cpu 32.5, max 90, 4 samples", p)
+	}
+	if p := points[1]; !p.Time.Equal(base.Add(5*time.Minute)) || p.Samples != 1 {
+		t.Errorf("second bucket = %+v", p)
+	}
+}
+
+func TestPickLevel(t *testing.T) {
+	now := base
+	s := newTestStore(t, config.Default(), &now)
+
+	tests := []struct {
+		ago  time.Duration
+		step time.Duration
+		want string
+	}{
+		{30 * time.Minute, 0, "raw"},
+		{30 * time.Minute, 5 * time.Second, "raw"},
+		{30 * time.Minute, time.Minute, "1m"},
+		{30 * time.Minute, 10 * time.Minute, "5m"},
+		{30 * time.Minute, 2 * time.Hour, "1h"},
+		{time.Hour, 0, "raw"},
+		// Сырых замеров так далеко нет — самый подробный из покрывающих
+		{2 * time.Hour, 0, "1m"},
+		{3 * 24 * time.Hour, time.Minute, "5m"},
+		{3 * 24 * time.Hour, 2 * time.Hour, "1h"},
+		{20 * 24 * time.Hour, 0, "1h"},
+		// Старше любого хранения — самый длинный уровень
+		{60 * 24 * time.Hour, 0, "1h"},
+	}
+	for _, tt := range tests {
+		if got := s.pickLevel(now.Add(-tt.ago), tt.step).Name; got != tt.want {
+			t.Errorf("pickLevel(now-%v, %v) = %s, want %s", tt.ago, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestQueryAcrossLevels(t *testing.T) {
+	now := base.Add(3 * time.Hour)
+	s := newTestStore(t, config.Default(), &now)
+	feed(s, base, 3*720) // три часа по 5s
+
+	tests := []struct {
+		name    string
+		from    time.Time
+		step    time.Duration
+		source  string
+		count   int
+		spacing time.Duration
+		samples int
+	}{
+		{"last half hour", now.Add(-30 * time.Minute), 0, "raw", 360, 5 * time.Second, 1},
+		// Сырые замеры хранятся час — дальше берётся 1m вместе с незакрытой минутой
+		{"last two hours", now.Add(-2 * time.Hour), 0, "1m", 120, time.Minute, 12},
+		{"downsampled", base, 10 * time.Minute, "5m", 18, 10 * time.Minute, 120},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, source := s.Query(tt.from, now, tt.step)
+			if source != tt.source || len(points) != tt.count {
+				t.Fatalf("got %d points from %s, want %d from %s", len(points), source, tt.count, tt.source)
+			}
+			for i, p := range points {
+				if i > 0 && p.Time.Sub(points[i-1].Time) != tt.spacing {
+					t.Fatalf("points %d and %d are %v apart, want %v", i-1, i, p.Time.Sub(points[i-1].Time), tt.spacing)
+				}
+				if p.Samples != tt.samples || p.Memory != 40 {
+					t.Errorf("points[%d] = %+v, want %d samples", i, p, tt.samples)
+				}
+				if tt.samples > 1 && (p.CPU != 5.5 || p.CPUMax != 11) {
+					t.Errorf("points[%d]: cpu %v max %v, want 5.5 and 11", i, p.CPU, p.CPUMax)
+				}
+			}
+		})
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	cfg := config.Default()
+	cfg.History.PersistFile = filepath.Join(t.TempDir(), "state", "history.json")
+	now := base.Add(2 * time.Hour)
+
+	s := newTestStore(t, cfg, &now)
+	feed(s, base, 2*720)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Рестарт: новое хранилище отдаёт то же самое
+	restored := newTestStore(t, cfg, &now)
+	for _, step := range []time.Duration{0, time.Minute, 5 * time.Minute} {
+		from := now.Add(-50 * time.Minute)
+		want, wantSource := s.Query(from, now, step)
+		got, source := restored.Query(from, now, step)
+		if source != wantSource || len(got) != len(want) {
+			t.Fatalf("step %v: got %d points from %s, want %d from %s", step, len(got), source, len(want), wantSource)
+		}
+		for i := range want {
+			if !got[i].Time.Equal(want[i].Time) || got[i].CPU != want[i].CPU || got[i].Samples != want[i].Samples {
+				t.Errorf("step %v: points[%d] = %+v, want %+v", step, i, got[i], want[i])
+			}
+		}
+	}
+
+	// Восстановлены незакрытые интервалы и время последнего замера
+	if acc := restored.levels[2].Acc; acc.Sum.Samples != 60 || !acc.Start.Equal(base.Add(115*time.Minute)) {
+		t.Errorf("restored 5m interval = %+v, want 60 samples from 11:55", acc)
+	}
+	last := base.Add(time.Duration(2*720-1) * 5 * time.Second)
+	restored.Add(Point{Time: last.Add(time.Second)})
+	restored.Add(Point{Time: last.Add(5 * time.Second)})
+	if points := restored.levels[0].Ring.rangeOf(last, now); len(points) != 2 || !points[1].Time.Equal(now) {
+		t.Errorf("raw after restart = %+v, want the last saved point and the one at %v", points, now)
+	}
+	if p := restored.levels[2].Ring.rangeOf(base.Add(115*time.Minute), now); len(p) != 1 || p[0].Samples != 60 {
+		t.Errorf("5m interval closed after restart = %+v, want 60 samples", p)
+	}
+
+	// Уровень с другим шагом не подхватывается, остальные — да
+	changed := *cfg
+	changed.History.Resolution = config.Duration(10 * time.Second)
+	other := newTestStore(t, &changed, &now)
+	if other.levels[0].Ring.Count != 0 || other.levels[1].Ring.Count != s.levels[1].Ring.Count {
+		t.Errorf("after a resolution change: raw %d, 1m %d", other.levels[0].Ring.Count, other.levels[1].Ring.Count)
+	}
+
+	if err := os.WriteFile(cfg.History.PersistFile, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStore(cfg); err == nil {
+		t.Error("corrupted history file accepted")
+	}
+}
+
+func TestSaveWithoutPersistFile(t *testing.T) {
+	now := base
+	s := newTestStore(t, config.Default(), &now)
+	feed(s, base, 3)
+	if err := s.Save(); err != nil {
+		t.Errorf("Save without persist_file: %v", err)
+	}
+}
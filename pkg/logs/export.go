@@ -0,0 +1,276 @@
+package logs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+const (
+	exportDefaultRange = time.Hour // from по умолчанию — час назад
+	exportFlushEvery   = 500       // записей между сбросами в соединение
+)
+
+// Форматы выгрузки: расширение файла и Content-Type
+var exportFormats = map[string]struct{ ext, contentType string }{
+	"ndjson": {"ndjson", "application/x-ndjson"},
+	"csv":    {"csv", "text/csv; charset=utf-8"},
+	"text":   {"log", "text/plain; charset=utf-8"},
+}
+
+// Export передаёт fn все записи юнитов, подходящие под фильтры, от старых к новым.
+// Журнал читается потоком, записи не накапливаются; filter.Lines и курсоры не используются.
+// Ошибка fn прекращает чтение и возвращается
+func (s *Service) Export(ctx context.Context, units []string, filter LogFilter, fn func(LogEntry) error) error {
+	matcher, err := NewMatcher(filter)
+	if err != nil {
+		return err
+	}
+
+	parser := newEntryParser(s.parsers, soleUnit(units))
+	var fnErr error
+	err = readJournal(ctx, journalArgs(units, filter), func(line []byte) bool {
+		entry, ok := parser.parse(line)
+		if !ok || !matcher.Match(entry) {
+			return true
+		}
+		fnErr = fn(entry)
+		return fnErr == nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+// exportLogs - выгрузка записей за период файлом (ndjson, csv или text, с gzip=1 — сжатым)
+func (h *Handler) exportLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	now := time.Now()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+	spec, ok := exportFormats[format]
+	if !ok {
+		http.Error(w, fmt.Sprintf("invalid format %q, expected ndjson, csv or text", format), http.StatusBadRequest)
+		return
+	}
+
+	from, to := now.Add(-exportDefaultRange), now
+	var err error
+	if v := query.Get("from"); v != "" {
+		if from, err = config.ParseTime(v, now); err != nil {
+			http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = config.ParseTime(v, now); err != nil {
+			http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	filter := LogFilter{
+		Level:    query.Get("level"),
+		MinLevel: query.Get("min_level"),
+		Since:    fmt.Sprintf("@%d", from.Unix()),
+		Until:    fmt.Sprintf("@%d", to.Unix()),
+		Search:   query.Get("search"),
+		Regex:    query.Get("regex"),
+		Query:    query.Get("q"),
+		Fields:   fieldParams(query),
+	}
+	if _, err := NewMatcher(filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	units, ok := h.requestUnits(w, r)
+	if !ok {
+		return
+	}
+	compress := query.Get("gzip") == "1" || query.Get("gzip") == "true"
+
+	unit := strings.Join(units, "+")
+	filename := fmt.Sprintf("%s_%s_%s.%s", unit, from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z"), spec.ext)
+	if compress {
+		filename += ".gz"
+	}
+
+	// Большой период выгружается дольше server.write_timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	// Заголовки и файл открываются с первой записью: если journalctl упадёт сразу,
+	// клиент получит обычную ошибку, а не пустой файл
+	var out *exportWriter
+	open := func() {
+		if compress {
+			w.Header().Set("Content-Type", "application/gzip")
+		} else {
+			w.Header().Set("Content-Type", spec.contentType)
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Trailer", exportErrorTrailer)
+		out = newExportWriter(w, format, compress)
+	}
+
+	count := 0
+	err = h.service.Export(r.Context(), units, filter, func(entry LogEntry) error {
+		if out == nil {
+			open()
+		}
+		if err := out.write(entry); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			return out.flush()
+		}
+		return nil
+	})
+	if err != nil && out == nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		// Заголовки уже отправлены: файл обрезан, об этом говорят последняя запись и трейлер
+		log.Printf("⚠️  Logs: export of %s interrupted after %d entries: %v", unit, count, err)
+		if r.Context().Err() == nil {
+			out.writeError(err)
+		}
+	}
+	if out == nil {
+		open()
+	}
+	if err := out.close(); err != nil && r.Context().Err() == nil {
+		log.Printf("⚠️  Logs: export of %s: %v", unit, err)
+	}
+	if err != nil {
+		w.Header().Set(exportErrorTrailer, err.Error())
+	}
+}
+
+// exportWriter пишет записи в выбранном формате: формат → буфер → gzip → соединение
+type exportWriter struct {
+	w      http.ResponseWriter
+	gz     *gzip.Writer
+	buf    *bufio.Writer
+	csv    *csv.Writer
+	format string
+}
+
+// exportCSVHeader — колонки CSV; fields — поля записи объектом JSON
+var exportCSVHeader = []string{"timestamp", "level", "message", "unit", "pid", "priority", "syslog_identifier", "cursor", "fields"}
+
+// exportErrorTrailer — трейлер с ошибкой, из-за которой выгрузка оборвалась
+const exportErrorTrailer = "X-Export-Error"
+
+func newExportWriter(w http.ResponseWriter, format string, compress bool) *exportWriter {
+	out := &exportWriter{w: w, format: format}
+	var dst io.Writer = w
+	if compress {
+		out.gz = gzip.NewWriter(w)
+		dst = out.gz
+	}
+	out.buf = bufio.NewWriterSize(dst, 32*1024)
+	if format == "csv" {
+		out.csv = csv.NewWriter(out.buf)
+		out.csv.Write(exportCSVHeader)
+	}
+	return out
+}
+
+func (out *exportWriter) write(entry LogEntry) error {
+	switch out.format {
+	case "csv":
+		var fields string
+		if len(entry.Fields) > 0 {
+			data, _ := json.Marshal(entry.Fields)
+			fields = string(data)
+		}
+		return out.csv.Write([]string{
+			entry.Timestamp.Format(time.RFC3339Nano),
+			entry.Level,
+			entry.Message,
+			entry.Unit,
+			strconv.Itoa(entry.PID),
+			strconv.Itoa(entry.Priority),
+			entry.SyslogIdentifier,
+			entry.Cursor,
+			fields,
+		})
+	case "text":
+		_, err := fmt.Fprintf(out.buf, "%s [%s] %s\n", entry.Timestamp.Format("2006-01-02 15:04:05.000000"), entry.Level, entry.Message)
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = out.buf.Write(data)
+	return err
+}
+
+// writeError дописывает последнюю запись об обрыве выгрузки: в ndjson — {"error": ...},
+// в csv и text — запись с уровнем ERROR
+func (out *exportWriter) writeError(err error) {
+	message := "Export interrupted: " + err.Error()
+	if out.format == "ndjson" {
+		data, _ := json.Marshal(map[string]string{"error": message})
+		out.buf.Write(append(data, '\n'))
+		return
+	}
+	out.write(LogEntry{Timestamp: time.Now(), Level: "ERROR", Message: message})
+}
+
+// flush отправляет накопленное клиенту
+func (out *exportWriter) flush() error {
+	if out.csv != nil {
+		out.csv.Flush()
+		if err := out.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := out.buf.Flush(); err != nil {
+		return err
+	}
+	if out.gz != nil {
+		if err := out.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	if f, ok := out.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// close дописывает буферы и завершает gzip-поток
+func (out *exportWriter) close() error {
+	if err := out.flush(); err != nil {
+		return err
+	}
+	if out.gz != nil {
+		return out.gz.Close()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package logs
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// failingJournalctl выдаёт две записи и падает
+const failingJournalctl = `#!/bin/sh
+echo '{"__CURSOR":"c1","__REALTIME_TIMESTAMP":"1790000000000000","MESSAGE":"first {channel=telegram, chat_id=1}","_SYSTEMD_UNIT":"picoclaw.service"}'
+echo '{"__CURSOR":"c2","__REALTIME_TIMESTAMP":"1790000001000000","MESSAGE":"second","_SYSTEMD_UNIT":"picoclaw.service"}'
+echo 'Error was encountered while opening journal files: Input/output error' >&2
+exit 1
+`
+
+// export выполняет выгрузку через тестовый сервер и возвращает тело и трейлер с ошибкой
+func export(t *testing.T, format string) (string, string) {
+	t.Helper()
+	h := NewHandler(NewService(config.Default()))
+	srv := httptest.NewServer(http.HandlerFunc(h.exportLogs))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?format=" + format + "&from=1789999990&to=1790000010")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, body %s", resp.StatusCode, body)
+	}
+	return string(body), resp.Trailer.Get(exportErrorTrailer)
+}
+
+func TestExportInterrupted(t *testing.T) {
+	useFakeJournalctl(t, failingJournalctl)
+
+	body, trailer := export(t, "ndjson")
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 2 entries and an error record:\n%s", len(lines), body)
+	}
+	if !strings.HasPrefix(lines[2], `{"error":"Export interrupted: journalctl error`) || !strings.Contains(lines[2], "Input/output error") {
+		t.Errorf("last line = %s", lines[2])
+	}
+	if !strings.Contains(trailer, "Input/output error") {
+		t.Errorf("trailer = %q", trailer)
+	}
+
+	body, _ = export(t, "text")
+	if !strings.Contains(body, "[ERROR] Export interrupted: ") {
+		t.Errorf("text export has no error line:\n%s", body)
+	}
+}
+
+func TestExportCSVFields(t *testing.T) {
+	useFakeJournalctl(t, failingJournalctl)
+
+	body, _ := export(t, "csv")
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want a header, 2 entries and an error record", len(records))
+	}
+	last := len(exportCSVHeader) - 1
+	if records[0][last] != "fields" {
+		t.Errorf("header = %v", records[0])
+	}
+	if got := records[1][last]; got != `{"channel":"telegram","chat_id":"1"}` {
+		t.Errorf("fields = %s", got)
+	}
+	if got := records[2][last]; got != "" {
+		t.Errorf("entry without fields has %q", got)
+	}
+	if records[3][1] != "ERROR" || !strings.HasPrefix(records[3][2], "Export interrupted: ") {
+		t.Errorf("last record = %v", records[3])
+	}
+}
@@ -0,0 +1,466 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Язык запросов к логам:
+//
+//	level>=WARN AND (msg~"timeout|refused" OR unit:picoclaw-gateway) NOT msg:"heartbeat"
+//
+// Термы: слово или "строка" — подстрока сообщения или уровня без учёта регистра;
+// field OP value — сравнение поля. Операторы: NOT, AND (или просто пробел), OR,
+// скобки; приоритет NOT > AND > OR. Ключевые слова пишутся заглавными.
+//
+// Поля: msg (message), level, unit, ident (syslog_identifier), pid, priority, boot, cursor
+// и поля из сообщения: field.channel, field.chat_id (имя после "field." — с учётом регистра).
+// OP: ":" — подстрока (для чисел и уровня — равенство), "=" и "!=" — равенство
+// без учёта регистра, "~" — регулярное выражение Go, ">", ">=", "<", "<=" — для pid,
+// priority и level. Уровни сравниваются по шкале Severity: level>=WARN — WARN, ERROR
+// и FATAL, level:warning — то же, что level:WARN
+
+// Expr — узел разобранного запроса
+type Expr interface {
+	Eval(entry LogEntry) bool
+}
+
+// QueryError — ошибка разбора запроса с позицией (в байтах) в исходной строке
+type QueryError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query: %s at position %d", e.Msg, e.Pos)
+}
+
+// Ограничения запроса: разбор рекурсивный, а запрос приходит от клиента
+const (
+	maxQueryLength = 4096 // байт
+	maxQueryDepth  = 32   // вложенность скобок и NOT
+)
+
+// ParseQuery разбирает запрос. Пустой запрос даёт nil — под него подходит всё
+func ParseQuery(query string) (Expr, error) {
+	if len(query) > maxQueryLength {
+		return nil, &QueryError{Pos: maxQueryLength, Msg: fmt.Sprintf("query is longer than %d bytes", maxQueryLength)}
+	}
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, nil
+	}
+
+	p := &queryParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &QueryError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s", t)}
+	}
+	return expr, nil
+}
+
+// Узлы AST
+
+type andExpr []Expr
+
+func (a andExpr) Eval(entry LogEntry) bool {
+	for _, e := range a {
+		if !e.Eval(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+type orExpr []Expr
+
+func (o orExpr) Eval(entry LogEntry) bool {
+	for _, e := range o {
+		if e.Eval(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+type notExpr struct{ expr Expr }
+
+func (n notExpr) Eval(entry LogEntry) bool { return !n.expr.Eval(entry) }
+
+// textExpr — подстрока сообщения или уровня без учёта регистра
+type textExpr struct{ text string }
+
+func (t textExpr) Eval(entry LogEntry) bool {
+	return strings.Contains(strings.ToLower(entry.Message), t.text) ||
+		strings.Contains(strings.ToLower(entry.Level), t.text)
+}
+
+// fieldExpr — сравнение поля записи
+type fieldExpr struct {
+	field string // для полей из сообщения — "field."
+	name  string // имя поля из сообщения
+	op    string
+	value string // в нижнем регистре для строковых полей
+	num   int    // для pid, priority и level (Severity)
+	re    *regexp.Regexp
+}
+
+// Поля запроса и их типы
+var queryFields = map[string]string{
+	"msg":               "text",
+	"message":           "text",
+	"unit":              "text",
+	"ident":             "text",
+	"syslog_identifier": "text",
+	"boot":              "text",
+	"cursor":            "text",
+	entryFieldPrefix:    "text",
+	"level":             "level",
+	"pid":               "number",
+	"priority":          "number",
+}
+
+// entryFieldPrefix — префикс полей из сообщения (LogEntry.Fields)
+const entryFieldPrefix = "field."
+
+func newFieldExpr(field, op, value string, pos int) (Expr, error) {
+	var name string
+	if len(field) > len(entryFieldPrefix) && strings.EqualFold(field[:len(entryFieldPrefix)], entryFieldPrefix) {
+		field, name = entryFieldPrefix, field[len(entryFieldPrefix):]
+	} else {
+		field = strings.ToLower(field)
+	}
+	kind, ok := queryFields[field]
+	if !ok || (field == entryFieldPrefix && name == "") {
+		return nil, &QueryError{Pos: pos, Msg: fmt.Sprintf("unknown field %q", field)}
+	}
+	e := &fieldExpr{field: field, name: name, op: op, value: strings.ToLower(value)}
+
+	if op == "~" {
+		if kind == "number" {
+			return nil, &QueryError{Pos: pos, Msg: fmt.Sprintf("operator ~ is not supported for field %s", field)}
+		}
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, &QueryError{Pos: pos, Msg: fmt.Sprintf("invalid regex %q: %v", value, err)}
+		}
+		e.re = re
+		return e, nil
+	}
+
+	ordered := op == ">" || op == ">=" || op == "<" || op == "<="
+	switch kind {
+	case "text":
+		if ordered {
+			return nil, &QueryError{Pos: pos, Msg: fmt.Sprintf("operator %s is not supported for field %s", op, field+name)}
+		}
+	case "number":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, &QueryError{Pos: pos, Msg: fmt.Sprintf("field %s needs a number, got %q", field, value)}
+		}
+		e.num = n
+	case "level":
+		sev, err := ParseSeverity(value)
+		if err != nil {
+			return nil, &QueryError{Pos: pos, Msg: err.Error()}
+		}
+		e.num = int(sev)
+	}
+	return e, nil
+}
+
+func (f *fieldExpr) Eval(entry LogEntry) bool {
+	switch f.field {
+	case "pid":
+		return compareInt(entry.PID, f.op, f.num)
+	case "priority":
+		return compareInt(entry.Priority, f.op, f.num)
+	case "level":
+		if f.re != nil {
+			return f.re.MatchString(entry.Level)
+		}
+		return compareInt(int(EntrySeverity(entry)), f.op, f.num)
+	}
+
+	value := f.text(entry)
+	if f.re != nil {
+		return f.re.MatchString(value)
+	}
+	value = strings.ToLower(value)
+	switch f.op {
+	case ":":
+		return strings.Contains(value, f.value)
+	case "=":
+		return value == f.value
+	case "!=":
+		return value != f.value
+	}
+	return false
+}
+
+func (f *fieldExpr) text(entry LogEntry) string {
+	switch f.field {
+	case "msg", "message":
+		return entry.Message
+	case "unit":
+		return entry.Unit
+	case "ident", "syslog_identifier":
+		return entry.SyslogIdentifier
+	case "boot":
+		return entry.BootID
+	case "cursor":
+		return entry.Cursor
+	case entryFieldPrefix:
+		return entry.Fields[f.name]
+	}
+	return ""
+}
+
+func compareInt(a int, op string, b int) bool {
+	switch op {
+	case ":", "=":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+// Лексер
+
+const (
+	tokEOF = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// opChars — символы операторов сравнения; они же завершают слово
+const opChars = ":=!~<>"
+
+func lex(query string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			// Экранируются только \" и \\, остальные \ остаются как есть: msg~"\d+"
+			for ; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' && i+1 < len(query) && (query[i+1] == '"' || query[i+1] == '\\') {
+					i++
+				}
+				sb.WriteByte(query[i])
+			}
+			if i >= len(query) {
+				return nil, &QueryError{Pos: start, Msg: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+		case strings.IndexByte(opChars, c) >= 0:
+			start := i
+			op := string(c)
+			if i+1 < len(query) && query[i+1] == '=' && (c == '!' || c == '<' || c == '>') {
+				op += "="
+			}
+			if op == "!" {
+				return nil, &QueryError{Pos: start, Msg: `unexpected "!" (use != or NOT)`}
+			}
+			i += len(op)
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: start})
+		default:
+			start := i
+			for i < len(query) && !strings.ContainsRune(" \t\n\r()\""+opChars, rune(query[i])) {
+				i++
+			}
+			word := query[start:i]
+			kind := tokWord
+			switch word {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			tokens = append(tokens, token{kind: kind, text: word, pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(query)}), nil
+}
+
+// Парсер: рекурсивный спуск
+//
+//	or      = and { "OR" and }
+//	and     = unary { ["AND"] unary }
+//	unary   = "NOT" unary | primary
+//	primary = "(" or ")" | word op value | word | string
+
+type queryParser struct {
+	tokens []token
+	pos    int
+	depth  int // текущая вложенность скобок и NOT
+}
+
+// enter учитывает вложенную скобку или NOT; парная leave вызывается после разбора
+func (p *queryParser) enter(pos int) error {
+	if p.depth >= maxQueryDepth {
+		return &QueryError{Pos: pos, Msg: fmt.Sprintf("query is nested deeper than %d levels", maxQueryDepth)}
+	}
+	p.depth++
+	return nil
+}
+
+func (p *queryParser) leave() { p.depth-- }
+
+func (p *queryParser) peek() token { return p.tokens[p.pos] }
+
+func (p *queryParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := orExpr{left}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, right)
+	}
+	if len(terms) == 1 {
+		return left, nil
+	}
+	return terms, nil
+}
+
+func (p *queryParser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	terms := andExpr{left}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokNot, tokWord, tokString, tokLParen:
+			// Без оператора — тоже AND: `a NOT b` = a AND NOT b
+		default:
+			if len(terms) == 1 {
+				return left, nil
+			}
+			return terms, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, right)
+	}
+}
+
+func (p *queryParser) parseUnary() (Expr, error) {
+	if p.peek().kind == tokNot {
+		if err := p.enter(p.next().pos); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		if err := p.enter(t.pos); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &QueryError{Pos: closing.pos, Msg: fmt.Sprintf("expected \")\", got %s", closing)}
+		}
+		return expr, nil
+	case tokString:
+		return textExpr{text: strings.ToLower(t.text)}, nil
+	case tokWord:
+		if p.peek().kind != tokOp {
+			return textExpr{text: strings.ToLower(t.text)}, nil
+		}
+		op := p.next()
+		value := p.next()
+		if value.kind != tokWord && value.kind != tokString {
+			return nil, &QueryError{Pos: value.pos, Msg: fmt.Sprintf("expected value after %s%s, got %s", t.text, op.text, value)}
+		}
+		return newFieldExpr(t.text, op.text, value.text, t.pos)
+	}
+	return nil, &QueryError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s", t)}
+}
@@ -0,0 +1,99 @@
+package logs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLexQuotedString(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`"plain"`, `plain`},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"C:\\temp"`, `C:\temp`},
+		{`"\d+"`, `\d+`},
+		{`"a\.b"`, `a\.b`},
+		{`"\\d"`, `\d`},
+		{`"tab\t"`, `tab\t`},
+		{`"end\\"`, `end\`},
+	}
+	for _, tt := range tests {
+		tokens, err := lex(tt.query)
+		if err != nil {
+			t.Errorf("lex(%s): %v", tt.query, err)
+			continue
+		}
+		if tokens[0].kind != tokString || tokens[0].text != tt.want {
+			t.Errorf("lex(%s) = %q, want %q", tt.query, tokens[0].text, tt.want)
+		}
+	}
+
+	if _, err := lex(`"open \"`); err == nil {
+		t.Error(`lex("open \") accepted an unterminated string`)
+	}
+}
+
+func TestQueryRegexEscapes(t *testing.T) {
+	tests := []struct {
+		query   string
+		message string
+		want    bool
+	}{
+		{`msg~"chat_id=\d+"`, "sent to chat_id=42", true},
+		{`msg~"chat_id=\d+"`, "sent to chat_id=abc", false},
+		{`msg~"a\.b"`, "a.b", true},
+		{`msg~"a\.b"`, "axb", false},
+		{`msg~"\[ERROR\]"`, "[ERROR] boom", true},
+		{`msg~"\\\\server"`, `\\server\share`, true},
+		{`msg:"say \"hi\""`, `they say "hi" twice`, true},
+	}
+	for _, tt := range tests {
+		expr, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%s): %v", tt.query, err)
+			continue
+		}
+		if got := expr.Eval(LogEntry{Message: tt.message}); got != tt.want {
+			t.Errorf("%s on %q = %v, want %v", tt.query, tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestQueryLimits(t *testing.T) {
+	nested := func(open, close string, n int) string {
+		return strings.Repeat(open, n) + "a" + strings.Repeat(close, n)
+	}
+
+	for _, query := range []string{
+		nested("(", ")", maxQueryDepth),
+		nested("NOT ", "", maxQueryDepth),
+		nested("(NOT ", ")", maxQueryDepth/2),
+	} {
+		if _, err := ParseQuery(query); err != nil {
+			t.Errorf("ParseQuery(%.20s...) at the depth limit: %v", query, err)
+		}
+	}
+
+	for _, query := range []string{
+		nested("(", ")", maxQueryDepth+1),
+		nested("NOT ", "", maxQueryDepth+1),
+		nested("(NOT ", ")", maxQueryDepth/2+1),
+	} {
+		_, err := ParseQuery(query)
+		if err == nil || !strings.Contains(err.Error(), "nested deeper") {
+			t.Errorf("ParseQuery(%.20s...) = %v, want a depth error", query, err)
+		}
+	}
+
+	if _, err := ParseQuery(strings.Repeat("a ", maxQueryLength/2)); err != nil {
+		t.Errorf("query of %d bytes: %v", maxQueryLength, err)
+	}
+	for _, query := range []string{strings.Repeat("a", maxQueryLength+1), strings.Repeat("(", 1<<20)} {
+		_, err := ParseQuery(query)
+		if err == nil || !strings.Contains(err.Error(), "longer than") {
+			t.Errorf("query of %d bytes: %v, want a length error", len(query), err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Поля записи (LogEntry.Fields) заполняются по порядку: парсер строки (ключи JSON и logfmt,
+// прочие именованные группы regex-парсера), правила logs.fields, хвост сообщения —
+// JSON-объект или {key=value, ...} picoclaw — и пары key=value в тексте.
+// Поле, найденное раньше, не перезаписывается
+
+// fieldExtractor — правила извлечения полей из текста сообщения
+type fieldExtractor struct {
+	rules []*regexp.Regexp // logs.fields: именованные группы — имена полей
+}
+
+func newFieldExtractor(patterns []string) (*fieldExtractor, error) {
+	x := &fieldExtractor{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("field rule %q: %w", pattern, err)
+		}
+		x.rules = append(x.rules, re)
+	}
+	return x, nil
+}
+
+// fieldKeyPattern — имя поля в key=value
+var fieldKeyPattern = regexp.MustCompile(`^[A-Za-z_][\w.-]*$`)
+
+// fieldPairPattern — key=value или key="quoted value" внутри текста
+var fieldPairPattern = regexp.MustCompile(`(?:^|[\s,;(\[])([A-Za-z_][\w.-]*)=("(?:[^"\\]|\\.)*"|[^\s,;)\]]+)`)
+
+// extract дополняет fields полями из сообщения. Возвращает nil, если полей нет
+func (x *fieldExtractor) extract(message string, fields map[string]string) map[string]string {
+	set := func(key, value string) {
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+
+	for _, re := range x.rules {
+		matches := re.FindStringSubmatch(message)
+		if matches == nil {
+			continue
+		}
+		for i, name := range re.SubexpNames() {
+			if name != "" && matches[i] != "" {
+				set(name, matches[i])
+			}
+		}
+	}
+
+	if !strings.ContainsRune(message, '=') && !strings.HasSuffix(message, "}") {
+		return fields
+	}
+	text := splitFieldsTail(message, set)
+	for _, m := range fieldPairPattern.FindAllStringSubmatch(text, -1) {
+		set(m[1], unquoteField(m[2]))
+	}
+	return fields
+}
+
+// maxTailAttempts — сколько открывающих скобок проверяется в поисках хвоста с полями
+const maxTailAttempts = 8
+
+// splitFieldsTail передаёт в set поля хвоста сообщения — JSON-объекта или {key=value, ...} —
+// и возвращает текст перед хвостом (всё сообщение, если хвоста нет)
+func splitFieldsTail(message string, set func(key, value string)) string {
+	trimmed := strings.TrimRight(message, " ")
+	if !strings.HasSuffix(trimmed, "}") {
+		return message
+	}
+	start := 0
+	for attempt := 0; attempt < maxTailAttempts; attempt++ {
+		i := strings.IndexByte(trimmed[start:], '{')
+		if i < 0 {
+			break
+		}
+		i += start
+		tail := trimmed[i:]
+
+		var object map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(tail))
+		decoder.UseNumber()
+		if decoder.Decode(&object) == nil && decoder.InputOffset() == int64(len(tail)) {
+			flattenJSON("", object, set)
+			return message[:i]
+		}
+		if pairs := parseBracePairs(tail[1 : len(tail)-1]); pairs != nil {
+			for _, pair := range pairs {
+				set(pair[0], pair[1])
+			}
+			return message[:i]
+		}
+		start = i + 1
+	}
+	return message
+}
+
+// parseBracePairs разбирает поля picoclaw "key=value, key=value". Значение может содержать
+// ", " — тогда часть без key= продолжает предыдущее значение. nil — это не поля
+func parseBracePairs(content string) [][2]string {
+	var pairs [][2]string
+	for _, part := range strings.Split(content, ", ") {
+		key, value, ok := strings.Cut(part, "=")
+		if ok && fieldKeyPattern.MatchString(key) {
+			pairs = append(pairs, [2]string{key, value})
+			continue
+		}
+		if len(pairs) == 0 {
+			return nil
+		}
+		pairs[len(pairs)-1][1] += ", " + part
+	}
+	return pairs
+}
+
+// flattenJSON передаёт в set значения объекта; вложенные ключи соединяются точкой
+// (user.id), массивы записываются как JSON
+func flattenJSON(prefix string, value interface{}, set func(key, value string)) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flattenJSON(key, item, set)
+		}
+	case string:
+		set(prefix, v)
+	case nil:
+	case []interface{}:
+		data, _ := json.Marshal(v)
+		set(prefix, string(data))
+	default:
+		set(prefix, fmt.Sprint(v))
+	}
+}
+
+func unquoteField(value string) string {
+	if strings.HasPrefix(value, `"`) {
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+		return strings.Trim(value, `"`)
+	}
+	return value
+}
@@ -0,0 +1,77 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Matcher — LogFilter, подготовленный для проверки отдельных записей (стриминг)
+type Matcher struct {
+	level    Severity // точный уровень
+	minLevel Severity // уровень не ниже
+	search   string
+	re       *regexp.Regexp
+	expr     Expr
+	fields   map[string]string
+}
+
+// NewMatcher компилирует фильтр. Ошибка — при неизвестном уровне, некорректном Regex или Query
+func NewMatcher(filter LogFilter) (*Matcher, error) {
+	m := &Matcher{search: strings.ToLower(filter.Search)}
+	if filter.Level != "" {
+		level, err := ParseSeverity(filter.Level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level: %w", err)
+		}
+		m.level = level
+	}
+	if filter.MinLevel != "" {
+		level, err := ParseSeverity(filter.MinLevel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min_level: %w", err)
+		}
+		m.minLevel = level
+	}
+	if filter.Regex != "" {
+		re, err := regexp.Compile(filter.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		m.re = re
+	}
+	expr, err := ParseQuery(filter.Query)
+	if err != nil {
+		return nil, err
+	}
+	m.expr = expr
+	m.fields = filter.Fields
+	return m, nil
+}
+
+// Match проверяет уровень (точно и не ниже минимального), подстроку без учёта регистра, регулярку,
+// поля и запрос
+func (m *Matcher) Match(entry LogEntry) bool {
+	if m.level != SeverityUnknown || m.minLevel != SeverityUnknown {
+		sev := EntrySeverity(entry)
+		if m.level != SeverityUnknown && sev != m.level {
+			return false
+		}
+		if sev < m.minLevel {
+			return false
+		}
+	}
+	if m.search != "" && !strings.Contains(strings.ToLower(entry.Message), m.search) &&
+		!strings.Contains(strings.ToLower(entry.Level), m.search) {
+		return false
+	}
+	if m.re != nil && !m.re.MatchString(entry.Message) {
+		return false
+	}
+	for name, value := range m.fields {
+		if !strings.EqualFold(entry.Fields[name], value) {
+			return false
+		}
+	}
+	return m.expr == nil || m.expr.Eval(entry)
+}
@@ -0,0 +1,265 @@
+package logs
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Subscription — подписка на общий поток логов юнита (см. Service.Subscribe)
+type Subscription struct {
+	// Entries закрывается после Close или когда подписчик не успевал читать
+	Entries <-chan LogEntry
+
+	entries     chan LogEntry
+	service     *Service
+	follower    *follower
+	wantBacklog bool // backlog ещё не отдан (follower загружает его при старте)
+	dropped     bool
+	closed      bool
+
+	// Для SubscribeUnits: подписки на каждый юнит и сигнал остановки пересылки
+	parts    []*Subscription
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// follower — один journalctl -f на юнит. Разбирает строки один раз и раздаёт записи
+// всем подписчикам. Всё состояние защищено Service.followMu
+type follower struct {
+	unit    string
+	cancel  context.CancelFunc
+	subs    map[*Subscription]struct{}
+	backlog []LogEntry
+	loaded  bool // backlog загружен из журнала
+	stopped bool
+}
+
+// Subscribe подключается к общему follower'у юнита, запуская его для первого подписчика.
+// С withBacklog подписчик сначала получает последние записи (logs.backlog).
+// Подписчик, чья очередь (logs.subscriber_buffer) переполнилась, отключается:
+// Entries закрывается, Dropped возвращает true. Follower останавливается,
+// когда уходит последний подписчик
+func (s *Service) Subscribe(unit string, withBacklog bool) *Subscription {
+	s.followMu.Lock()
+	defer s.followMu.Unlock()
+
+	f, ok := s.followers[unit]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		f = &follower{unit: unit, cancel: cancel, subs: make(map[*Subscription]struct{})}
+		s.followers[unit] = f
+		go s.runFollower(ctx, f)
+	}
+
+	ch := make(chan LogEntry, s.subscriberBuffer)
+	sub := &Subscription{Entries: ch, entries: ch, service: s, follower: f}
+	f.subs[sub] = struct{}{}
+
+	if withBacklog {
+		if f.loaded {
+			sub.sendBacklog(f.backlog)
+		} else {
+			sub.wantBacklog = true
+		}
+	}
+	return sub
+}
+
+// SubscribeUnits подписывается на несколько юнитов сразу: записи всех юнитов приходят
+// в один канал в порядке поступления. Для нескольких юнитов backlog не отдаётся — порядок
+// между их backlog'ами не определён, последние записи читайте через Query.
+// Если один из юнитов отключён за отставание, закрывается вся подписка
+func (s *Service) SubscribeUnits(units []string, withBacklog bool) *Subscription {
+	if len(units) == 1 {
+		return s.Subscribe(units[0], withBacklog)
+	}
+
+	ch := make(chan LogEntry, s.subscriberBuffer)
+	merged := &Subscription{Entries: ch, entries: ch, service: s, stop: make(chan struct{})}
+	for _, unit := range units {
+		merged.parts = append(merged.parts, s.Subscribe(unit, false))
+	}
+
+	var wg sync.WaitGroup
+	for _, part := range merged.parts {
+		wg.Add(1)
+		go func(part *Subscription) {
+			defer wg.Done()
+			defer merged.Close()
+			for entry := range part.Entries {
+				select {
+				case ch <- entry:
+				case <-merged.stop:
+					return
+				}
+			}
+		}(part)
+	}
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	return merged
+}
+
+// Close отписывается. Повторный вызов безопасен
+func (sub *Subscription) Close() {
+	if sub.parts != nil {
+		sub.stopOnce.Do(func() { close(sub.stop) })
+		for _, part := range sub.parts {
+			part.Close()
+		}
+		return
+	}
+	s := sub.service
+	s.followMu.Lock()
+	defer s.followMu.Unlock()
+	s.unsubscribe(sub)
+}
+
+// Dropped сообщает, что подписчик был отключён из-за переполненной очереди
+func (sub *Subscription) Dropped() bool {
+	for _, part := range sub.parts {
+		if part.Dropped() {
+			return true
+		}
+	}
+	s := sub.service
+	s.followMu.Lock()
+	defer s.followMu.Unlock()
+	return sub.dropped
+}
+
+// sendBacklog кладёт в очередь последние записи, сколько поместится
+func (sub *Subscription) sendBacklog(backlog []LogEntry) {
+	if n := cap(sub.entries); len(backlog) > n {
+		backlog = backlog[len(backlog)-n:]
+	}
+	for _, entry := range backlog {
+		sub.entries <- entry
+	}
+}
+
+// Followers — количество запущенных follower'ов
+func (s *Service) Followers() int {
+	s.followMu.Lock()
+	defer s.followMu.Unlock()
+	return len(s.followers)
+}
+
+// DroppedSubscribers — сколько подписчиков отключено за медленное чтение
+func (s *Service) DroppedSubscribers() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// unsubscribe закрывает очередь и останавливает follower без подписчиков; вызывается под followMu
+func (s *Service) unsubscribe(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.entries)
+
+	f := sub.follower
+	delete(f.subs, sub)
+	if len(f.subs) == 0 && !f.stopped {
+		f.stopped = true
+		f.cancel()
+		delete(s.followers, f.unit)
+	}
+}
+
+// dispatch сохраняет запись в backlog и раздаёт подписчикам
+func (s *Service) dispatch(f *follower, entry LogEntry) {
+	s.followMu.Lock()
+	defer s.followMu.Unlock()
+	if f.stopped {
+		return
+	}
+
+	if s.backlog > 0 {
+		if len(f.backlog) >= s.backlog {
+			f.backlog = append(f.backlog[:0], f.backlog[len(f.backlog)-s.backlog+1:]...)
+		}
+		f.backlog = append(f.backlog, entry)
+	}
+
+	for sub := range f.subs {
+		select {
+		case sub.entries <- entry:
+		default:
+			sub.dropped = true
+			atomic.AddUint64(&s.dropped, 1)
+			log.Printf("⚠️  Logs: slow subscriber of %s dropped", f.unit)
+			s.unsubscribe(sub)
+		}
+	}
+}
+
+// runFollower загружает backlog и читает журнал до остановки follower'а.
+// Чтение продолжается с курсора последней прочитанной записи, поэтому записи между
+// загрузкой backlog и запуском journalctl (и между перезапусками) не теряются.
+// Если journalctl завершился, перезапускается с растущей задержкой
+func (s *Service) runFollower(ctx context.Context, f *follower) {
+	var backlog []LogEntry
+	var cursor string
+	if s.backlog > 0 {
+		loadCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		page, err := s.Query(loadCtx, []string{f.unit}, LogFilter{Lines: s.backlog})
+		cancel()
+		if err != nil {
+			log.Printf("⚠️  Logs: backlog of %s: %v", f.unit, err)
+		}
+		backlog, cursor = page.Entries, page.AfterCursor
+	}
+
+	s.followMu.Lock()
+	f.backlog = backlog
+	f.loaded = true
+	for sub := range f.subs {
+		if sub.wantBacklog {
+			sub.wantBacklog = false
+			sub.sendBacklog(backlog)
+		}
+	}
+	s.followMu.Unlock()
+
+	backoff := 5 * time.Second
+	for {
+		position := []string{"-n", "0"}
+		if cursor != "" {
+			position = []string{"--after-cursor=" + cursor}
+		}
+		started := time.Now()
+		err := s.follow(ctx, f.unit, position, func(entry LogEntry) {
+			if entry.Cursor != "" {
+				cursor = entry.Cursor
+			}
+			s.dispatch(f, entry)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		// Курсор пропал из журнала (ротация) — читаем с конца
+		if strings.Contains(err.Error(), "cursor") {
+			cursor = ""
+		}
+
+		if time.Since(started) > time.Minute {
+			backoff = 5 * time.Second
+		}
+		log.Printf("⚠️  Logs: follower for %s stopped: %v (restarting in %s)", f.unit, err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package logs
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// fixedJournalctl — журнал из записей c1..c5. Понимает --reverse, --cursor и --after-cursor;
+// с -f дописывает следующие записи каждые 20 мс, а с -n 0 начинает с c10, как будто
+// записи между чтением и запуском потерялись. Аргументы вызовов пишутся в $FAKE_JOURNALCTL_LOG
+const fixedJournalctl = `#!/bin/sh
+record() {
+	printf '{"__CURSOR":"c%d","__REALTIME_TIMESTAMP":"%d000000","MESSAGE":"entry %d","_SYSTEMD_UNIT":"picoclaw.service"}\n' $1 $((1771668000 + $1)) $1
+}
+[ -z "$FAKE_JOURNALCTL_LOG" ] || echo "$*" >>"$FAKE_JOURNALCTL_LOG"
+from=1 to=5 reverse= follow=
+for arg; do
+	case $arg in
+	--reverse) reverse=1 ;;
+	--cursor=c*) to=${arg#--cursor=c} ;;
+	--after-cursor=c*) from=$((${arg#--after-cursor=c} + 1)) ;;
+	-f) follow=1 ;;
+	esac
+done
+if [ -n "$follow" ]; then
+	case " $* " in *" -n 0 "*) from=10 ;; esac
+	i=$from
+	while :; do
+		record $i
+		i=$((i+1))
+		sleep 0.02
+	done
+fi
+if [ -n "$reverse" ]; then
+	i=$to
+	while [ $i -ge 1 ]; do record $i; i=$((i-1)); done
+else
+	i=$from
+	while [ $i -le $to ]; do record $i; i=$((i+1)); done
+fi
+`
+
+// newFollowerTest подставляет fixedJournalctl и возвращает сервис и файл с вызовами journalctl
+func newFollowerTest(t *testing.T, backlog, buffer int) (*Service, string) {
+	t.Helper()
+	useFakeJournalctl(t, fixedJournalctl)
+	calls := filepath.Join(t.TempDir(), "calls")
+	t.Setenv("FAKE_JOURNALCTL_LOG", calls)
+
+	cfg := config.Default()
+	cfg.Logs.Backlog = backlog
+	cfg.Logs.SubscriberBuffer = buffer
+	return NewService(cfg), calls
+}
+
+// receive читает n записей подписки
+func receive(t *testing.T, sub *Subscription, n int) []string {
+	t.Helper()
+	var cursors []string
+	for len(cursors) < n {
+		select {
+		case entry, ok := <-sub.Entries:
+			if !ok {
+				t.Fatalf("entries closed after %v", cursors)
+			}
+			cursors = append(cursors, entry.Cursor)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no entry after %v", cursors)
+		}
+	}
+	return cursors
+}
+
+// followCalls — сколько раз journalctl запускался с -f
+func followCalls(t *testing.T, calls string) int {
+	t.Helper()
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasSuffix(line, " -f") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSubscribeBacklogReplay(t *testing.T) {
+	s, calls := newFollowerTest(t, 3, 16)
+
+	// Backlog — последние записи журнала, дальше поток продолжается с курсора без пропусков
+	sub := s.Subscribe("picoclaw", true)
+	defer sub.Close()
+	if got := strings.Join(receive(t, sub, 5), " "); got != "c3 c4 c5 c6 c7" {
+		t.Fatalf("first subscriber got %s, want c3 c4 c5 c6 c7", got)
+	}
+
+	// Поздний подписчик получает текущий backlog follower'а, журнал второй раз не читается
+	late := s.Subscribe("picoclaw", true)
+	defer late.Close()
+	got := receive(t, late, 5)
+	for i := 1; i < len(got); i++ {
+		if cursorNumber(t, got[i]) != cursorNumber(t, got[i-1])+1 {
+			t.Fatalf("late subscriber got %v, want consecutive entries", got)
+		}
+	}
+	// c7 уже был прочитан, значит backlog из трёх записей начинается не раньше c5
+	if cursorNumber(t, got[0]) < 5 {
+		t.Errorf("late subscriber backlog starts at %s, want the last three entries", got[0])
+	}
+
+	data, _ := os.ReadFile(calls)
+	if n := followCalls(t, calls); n != 1 || !strings.Contains(string(data), "--after-cursor=c5 -f") {
+		t.Errorf("journalctl calls:\n%s", data)
+	}
+}
+
+// cursorNumber — номер записи fixedJournalctl по её курсору
+func cursorNumber(t *testing.T, cursor string) int {
+	t.Helper()
+	n, err := strconv.Atoi(strings.TrimPrefix(cursor, "c"))
+	if err != nil {
+		t.Fatalf("cursor %q: %v", cursor, err)
+	}
+	return n
+}
+
+func TestSubscribeFanOut(t *testing.T) {
+	s, calls := newFollowerTest(t, 0, 64)
+
+	a := s.Subscribe("picoclaw", false)
+	defer a.Close()
+	b := s.Subscribe("picoclaw", false)
+	defer b.Close()
+
+	// Оба подписчика получают одни и те же записи одного journalctl
+	fromA := receive(t, a, 10)
+	fromB := receive(t, b, 5)
+	offset := -1
+	for i, cursor := range fromA {
+		if cursor == fromB[0] {
+			offset = i
+			break
+		}
+	}
+	if offset < 0 || offset+len(fromB) > len(fromA) {
+		t.Fatalf("a got %v, b got %v", fromA, fromB)
+	}
+	for i, cursor := range fromB {
+		if fromA[offset+i] != cursor {
+			t.Fatalf("a got %v, b got %v", fromA, fromB)
+		}
+	}
+	if fromA[0] != "c10" {
+		t.Errorf("without backlog the stream starts at %s, want the end of the journal", fromA[0])
+	}
+
+	if s.Followers() != 1 || followCalls(t, calls) != 1 {
+		t.Errorf("followers = %d, journalctl -f started %d times", s.Followers(), followCalls(t, calls))
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	s, _ := newFollowerTest(t, 0, 2)
+
+	slow := s.Subscribe("picoclaw", false)
+	fast := s.Subscribe("picoclaw", false)
+	defer fast.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !slow.Dropped() {
+		if time.Now().After(deadline) {
+			t.Fatal("slow subscriber was not dropped")
+		}
+		receive(t, fast, 1)
+	}
+
+	// Что успело попасть в очередь, читается, после чего Entries закрыт
+	n := 0
+	for range slow.Entries {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("dropped subscriber drained %d entries, want 2", n)
+	}
+	if s.DroppedSubscribers() != 1 {
+		t.Errorf("DroppedSubscribers = %d, want 1", s.DroppedSubscribers())
+	}
+	slow.Close()
+
+	// Остальные подписчики и follower работают дальше
+	receive(t, fast, 3)
+	if s.Followers() != 1 || fast.Dropped() {
+		t.Errorf("followers = %d, fast dropped = %v", s.Followers(), fast.Dropped())
+	}
+}
+
+func TestFollowerStopsWithLastSubscriber(t *testing.T) {
+	s, calls := newFollowerTest(t, 0, 64)
+
+	a := s.Subscribe("picoclaw", false)
+	b := s.Subscribe("picoclaw", false)
+	other := s.Subscribe("worker", false)
+	if s.Followers() != 2 {
+		t.Fatalf("followers = %d, want 2 (one per unit)", s.Followers())
+	}
+	receive(t, a, 1)
+
+	a.Close()
+	a.Close()
+	for range a.Entries {
+		// Дочитываем оставшееся в очереди: после Close канал закрыт
+	}
+	if s.Followers() != 2 {
+		t.Errorf("follower stopped while b is subscribed")
+	}
+	receive(t, b, 3)
+
+	b.Close()
+	if s.Followers() != 1 {
+		t.Errorf("followers = %d after the last picoclaw subscriber left, want 1", s.Followers())
+	}
+	other.Close()
+	if s.Followers() != 0 {
+		t.Errorf("followers = %d, want 0", s.Followers())
+	}
+
+	// Новый подписчик запускает новый journalctl
+	again := s.Subscribe("picoclaw", false)
+	defer again.Close()
+	receive(t, again, 1)
+	if n := followCalls(t, calls); n != 3 {
+		t.Errorf("journalctl -f started %d times, want 3", n)
+	}
+}
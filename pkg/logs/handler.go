@@ -3,14 +3,27 @@ package logs
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/auth"
+)
+
+const (
+	streamHeartbeat = 15 * time.Second // комментарий-пинг в SSE потоке
+	streamRetry     = 3 * time.Second  // задержка переподключения EventSource
+	replayPageSize  = 500              // записей за одно чтение при возобновлении
 )
 
 type Handler struct {
-	service *Service
+	service     *Service
+	subscribers int64 // активные SSE подписчики
 }
 
 func NewHandler(service *Service) *Handler {
@@ -22,6 +35,9 @@ func NewHandler(service *Service) *Handler {
 // RegisterRoutes регистрирует роуты для API логов
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/logs", func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Allow(w, r, auth.PermLogsRead) {
+			return
+		}
 		if r.Method == http.MethodGet {
 			h.getLogs(w, r)
 		} else {
@@ -29,13 +45,39 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 		}
 	})
 	mux.HandleFunc("/api/logs/units", func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Allow(w, r, auth.PermLogsRead) {
+			return
+		}
 		if r.Method == http.MethodGet {
 			h.getUnits(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	mux.HandleFunc("/api/logs/export", func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Allow(w, r, auth.PermLogsRead) {
+			return
+		}
+		if r.Method == http.MethodGet {
+			h.exportLogs(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/logs/histogram", func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Allow(w, r, auth.PermLogsRead) {
+			return
+		}
+		if r.Method == http.MethodGet {
+			h.getHistogram(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
 	mux.HandleFunc("/api/logs/stream", func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Allow(w, r, auth.PermLogsRead) {
+			return
+		}
 		if r.Method == http.MethodGet {
 			h.streamLogs(w, r)
 		} else {
@@ -48,7 +90,7 @@ func (h *Handler) getLogs(w http.ResponseWriter, r *http.Request) {
 	// Параметры запроса
 	query := r.URL.Query()
 
-	// Количество строк (по умолчанию 100)
+	// Количество подходящих записей (по умолчанию 100)
 	lines := 100
 	if l := query.Get("lines"); l != "" {
 		if n, err := strconv.Atoi(l); err == nil && n > 0 {
@@ -58,10 +100,30 @@ func (h *Handler) getLogs(w http.ResponseWriter, r *http.Request) {
 
 	// Фильтры
 	filter := LogFilter{
-		Lines:  lines,
-		Level:  query.Get("level"),
-		Since:  query.Get("since"),
-		Search: query.Get("search"),
+		Lines:    lines,
+		Level:    query.Get("level"),
+		MinLevel: query.Get("min_level"),
+		Since:    query.Get("since"),
+		Until:    query.Get("until"),
+		Search:   query.Get("search"),
+		Regex:    query.Get("regex"),
+		Query:    query.Get("q"),
+		Fields:   fieldParams(query),
+
+		AfterCursor:  query.Get("after_cursor"),
+		BeforeCursor: query.Get("before_cursor"),
+	}
+	if filter.AfterCursor != "" && filter.BeforeCursor != "" {
+		http.Error(w, "after_cursor and before_cursor are mutually exclusive", http.StatusBadRequest)
+		return
+	}
+	if _, err := NewMatcher(filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	units, ok := h.requestUnits(w, r)
+	if !ok {
+		return
 	}
 
 	// Таймаут для запроса
@@ -69,7 +131,11 @@ func (h *Handler) getLogs(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	// Получаем логи
-	entries, err := h.service.GetLogs(ctx, filter)
+	page, err := h.service.Query(ctx, units, filter)
+	if errors.Is(err, ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -77,9 +143,15 @@ func (h *Handler) getLogs(w http.ResponseWriter, r *http.Request) {
 
 	// Формируем ответ
 	response := LogResponse{
-		Entries: entries,
-		Total:   len(entries),
-		Unit:    h.service.unit,
+		Entries:      page.Entries,
+		Total:        len(page.Entries),
+		Unit:         units[0],
+		Units:        units,
+		BeforeCursor: page.BeforeCursor,
+		AfterCursor:  page.AfterCursor,
+		HasMore:      page.HasMore,
+		Scanned:      page.Scanned,
+		Partial:      page.Partial,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -98,7 +170,8 @@ func (h *Handler) getUnits(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"units": units,
+		"units":   units,
+		"allowed": h.service.Units(), // можно передать в unit
 	})
 }
 
@@ -112,9 +185,21 @@ func (h *Handler) streamLogs(w http.ResponseWriter, r *http.Request) {
 
 	// Фильтры
 	query := r.URL.Query()
-	filter := LogFilter{
-		Level:  query.Get("level"),
-		Search: query.Get("search"),
+	matcher, err := NewMatcher(LogFilter{
+		Level:    query.Get("level"),
+		MinLevel: query.Get("min_level"),
+		Search:   query.Get("search"),
+		Regex:    query.Get("regex"),
+		Query:    query.Get("q"),
+		Fields:   fieldParams(query),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	units, ok := h.requestUnits(w, r)
+	if !ok {
+		return
 	}
 
 	// Заголовки SSE
@@ -129,63 +214,154 @@ func (h *Handler) streamLogs(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
 		return
 	}
+	// server.write_timeout рассчитан на обычные ответы, поток живёт, пока подключён клиент
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
 
 	// Контекст для отмены
 	ctx := r.Context()
 
-	// Функция для отправки события
+	atomic.AddInt64(&h.subscribers, 1)
+	defer atomic.AddInt64(&h.subscribers, -1)
+
+	// Функция для отправки события; id — курсор журнала для возобновления
 	sendEvent := func(entry LogEntry) {
-		select {
-		case <-ctx.Done():
-			return // Контекст отменен, не пишем
-		default:
-		}
 		data, _ := json.Marshal(entry)
+		if entry.Cursor != "" {
+			fmt.Fprintf(w, "id: %s\n", entry.Cursor)
+		}
 		fmt.Fprintf(w, "data: %s\n\n", data)
 		flusher.Flush()
 	}
 
-	// Запускаем чтение логов
-	go func() {
-		err := h.service.FollowLogs(ctx, func(entry LogEntry) {
-			// Применяем фильтры
-			if filter.Level != "" && entry.Level != filter.Level {
-				return
-			}
-			if filter.Search != "" && !contains(entry.Message, filter.Search) {
-				return
+	// При переподключении EventSource присылает курсор последнего полученного события
+	lastEventID := r.Header.Get("Last-Event-ID")
+
+	// Общие follower'ы юнитов. Без Last-Event-ID — последние записи, затем новые.
+	// С ним подписываемся до догоняющего чтения, чтобы не потерять записи между ними.
+	// Для нескольких юнитов последние записи тоже читаются из журнала: так они идут по времени
+	sub := h.service.SubscribeUnits(units, lastEventID == "")
+	defer sub.Close()
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	flusher.Flush()
+
+	// Записи, отправленные при чтении из журнала; они же могут прийти от follower'а.
+	// caughtUp — юниты, от которых уже пришла новая запись: дальше пересечений нет
+	replayed := make(map[string]bool)
+	caughtUp := make(map[string]bool)
+	sendRead := func(entries []LogEntry) {
+		for _, entry := range entries {
+			replayed[entry.Cursor] = true
+			if matcher.Match(entry) {
+				sendEvent(entry)
 			}
-			sendEvent(entry)
+		}
+	}
+	streamError := func(message string) {
+		sendEvent(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "ERROR",
+			Message:   "Log stream error: " + message,
 		})
+	}
 
-		// Отправляем ошибку только если соединение еще открыто
+	switch {
+	case lastEventID != "":
+		filter := LogFilter{Lines: replayPageSize, AfterCursor: lastEventID}
+		for {
+			page, err := h.service.Query(ctx, units, filter)
+			if err != nil {
+				if ctx.Err() == nil {
+					streamError("cannot resume: " + err.Error())
+				}
+				break
+			}
+			sendRead(page.Entries)
+			if !page.HasMore {
+				break
+			}
+			filter.AfterCursor = page.AfterCursor
+		}
+	case len(units) > 1 && h.service.backlog > 0:
+		page, err := h.service.Query(ctx, units, LogFilter{Lines: h.service.backlog})
 		if err != nil {
-			select {
-			case <-ctx.Done():
-				// Соединение закрыто, не пишем
-			default:
-				sendEvent(LogEntry{
-					Timestamp: time.Now(),
-					Level:     "ERROR",
-					Message:   "Log stream error: " + err.Error(),
-				})
+			if ctx.Err() == nil {
+				streamError("cannot read recent entries: " + err.Error())
 			}
+			break
 		}
-	}()
+		sendRead(page.Entries)
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
 
-	// Ждем пока клиент не закроет соединение
-	<-ctx.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			// Комментарий SSE не виден клиенту, но не даёт прокси закрыть соединение
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case entry, ok := <-sub.Entries:
+			if !ok {
+				// Клиент не успевал читать и был отключён от follower'а
+				streamError("client too slow, stream closed")
+				return
+			}
+			if len(replayed) > 0 && !caughtUp[entry.Unit] {
+				if replayed[entry.Cursor] {
+					continue
+				}
+				caughtUp[entry.Unit] = true
+			}
+			if matcher.Match(entry) {
+				sendEvent(entry)
+			}
+		}
+	}
 }
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && indexOf(s, substr) >= 0)
+// requestUnits — юниты из повторяемого параметра unit, по умолчанию logs.unit.
+// На юнит вне logs.units отвечает 403
+func (h *Handler) requestUnits(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var units []string
+	seen := make(map[string]bool)
+	for _, unit := range r.URL.Query()["unit"] {
+		if unit == "" || seen[unit] {
+			continue
+		}
+		if !h.service.Allowed(unit) {
+			http.Error(w, fmt.Sprintf("unit %q is not allowed", unit), http.StatusForbidden)
+			return nil, false
+		}
+		seen[unit] = true
+		units = append(units, unit)
+	}
+	if len(units) == 0 {
+		units = []string{h.service.unit}
+	}
+	return units, true
 }
 
-func indexOf(s, substr string) int {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
+// fieldParams — фильтр по полям записей из параметров field.<имя>=значение
+func fieldParams(query url.Values) map[string]string {
+	var fields map[string]string
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, entryFieldPrefix)
+		if !ok || name == "" || len(values) == 0 {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string)
 		}
+		fields[name] = values[0]
 	}
-	return -1
+	return fields
+}
+
+// Subscribers возвращает количество открытых SSE стримов
+func (h *Handler) Subscribers() int {
+	return int(atomic.LoadInt64(&h.subscribers))
 }
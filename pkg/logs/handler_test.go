@@ -0,0 +1,69 @@
+package logs
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// tickingJournalctl — journalctl без журнала: с -f выдаёт запись каждые 50 мс, иначе ничего
+const tickingJournalctl = `#!/bin/sh
+case " $* " in *" -f "*) ;; *) exit 0 ;; esac
+i=0
+while :; do
+	i=$((i+1))
+	printf '{"__CURSOR":"c%d","__REALTIME_TIMESTAMP":"%d000000","MESSAGE":"tick %d","_SYSTEMD_UNIT":"picoclaw.service"}\n' $i $(date +%s) $i
+	sleep 0.05
+done
+`
+
+// useFakeJournalctl подставляет в PATH journalctl со скриптом script
+func useFakeJournalctl(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "journalctl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestStreamOutlivesWriteTimeout(t *testing.T) {
+	useFakeJournalctl(t, tickingJournalctl)
+
+	h := NewHandler(NewService(config.Default()))
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(h.streamLogs))
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	started := time.Now()
+	reader := bufio.NewReader(resp.Body)
+	for time.Since(started) < 5*srv.Config.WriteTimeout {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream closed after %s: %v", time.Since(started).Round(time.Millisecond), err)
+		}
+		if strings.Contains(line, "Log stream error") {
+			t.Fatalf("stream error: %s", line)
+		}
+	}
+}
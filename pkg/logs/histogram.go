@@ -0,0 +1,378 @@
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+const (
+	histogramDefaultRange  = time.Hour       // since по умолчанию — час назад
+	histogramDefaultBucket = "1m"            // ширина корзины по умолчанию
+	histogramSettle        = 5 * time.Second // запас на запоздавшие записи: корзина моложе не кэшируется
+	maxHistogramBuckets    = 1500            // корзин в одном запросе (сутки по минуте)
+	maxHistogramSeries     = 32              // рядов в кэше (юниты, фильтры, group_by, bucket)
+)
+
+// HistogramOptions — период, ширина корзины и группировка гистограммы
+type HistogramOptions struct {
+	Since   time.Time
+	Until   time.Time     // нулевое или будущее — сейчас
+	Bucket  time.Duration // кратна секунде
+	GroupBy string        // level, unit или field.<имя>
+}
+
+// Histogram — число записей по корзинам времени и группам
+type Histogram struct {
+	Units   []string          `json:"units"`
+	Bucket  string            `json:"bucket"`
+	GroupBy string            `json:"group_by"`
+	Since   time.Time         `json:"since"` // начало первой корзины
+	Until   time.Time         `json:"until"`
+	Groups  []string          `json:"groups"` // встретившиеся группы; уровни — по возрастанию серьёзности
+	Buckets []HistogramBucket `json:"buckets"`
+	Scanned int               `json:"scanned"` // сколько записей журнала просмотрено
+	Cached  int               `json:"cached"`  // сколько корзин взято из кэша
+	Partial bool              `json:"partial,omitempty"`
+}
+
+// HistogramBucket — корзина [Time, Time+bucket)
+type HistogramBucket struct {
+	Time   time.Time      `json:"time"`
+	Total  int            `json:"total"`
+	Counts map[string]int `json:"counts"`
+}
+
+// Histogram считает записи юнитов, подходящие под фильтры, по корзинам времени.
+// Корзины выровнены по unix-времени. Закрытые корзины кэшируются, журнал читается
+// от первой корзины, которой нет в кэше. При остановке по logs.query_budget кэшируются
+// корзины, которые журнал успел пройти, — повторный запрос продолжит с них
+func (s *Service) Histogram(ctx context.Context, units []string, filter LogFilter, opts HistogramOptions) (Histogram, error) {
+	if len(units) == 0 {
+		return Histogram{}, errors.New("no units")
+	}
+	matcher, err := NewMatcher(filter)
+	if err != nil {
+		return Histogram{}, err
+	}
+	group, err := histogramGroup(opts.GroupBy)
+	if err != nil {
+		return Histogram{}, err
+	}
+	size := int64(opts.Bucket / time.Second)
+	if size < 1 {
+		return Histogram{}, errors.New("bucket must be at least 1s")
+	}
+
+	now := time.Now()
+	until := opts.Until
+	if until.IsZero() || until.After(now) {
+		until = now
+	}
+	first := opts.Since.Unix() / size * size
+	count := int((until.Unix()-first)/size) + 1
+	if opts.Since.After(until) || count <= 0 {
+		// Период целиком в будущем — записей в нём ещё нет
+		return Histogram{GroupBy: opts.GroupBy, Since: opts.Since, Until: until, Groups: []string{}, Buckets: []HistogramBucket{}}, nil
+	}
+
+	key := histogramKey(units, filter, opts.GroupBy, size)
+	counts := make([]map[string]int, count)
+	hist := Histogram{GroupBy: opts.GroupBy, Since: time.Unix(first, 0), Until: until}
+	hist.Cached = s.histograms.load(key, first, size, counts)
+
+	if hist.Cached < count {
+		scanFrom := first + int64(hist.Cached)*size
+		for i := hist.Cached; i < count; i++ {
+			counts[i] = make(map[string]int)
+		}
+		scan := filter
+		scan.Since = fmt.Sprintf("@%d", scanFrom)
+		scan.Until = fmt.Sprintf("@%d", until.Unix()+1)
+
+		var deadline time.Time
+		if s.queryBudget > 0 {
+			deadline = time.Now().Add(s.queryBudget)
+		}
+		reached := scanFrom // докуда прочитан журнал (unix-время)
+		parser := newEntryParser(s.parsers, soleUnit(units))
+		err := readJournal(ctx, journalArgs(units, scan), func(line []byte) bool {
+			entry, ok := parser.parse(line)
+			if !ok {
+				return true
+			}
+			hist.Scanned++
+			sec := entry.Timestamp.Unix()
+			if sec >= scanFrom && entry.Timestamp.Before(until) && matcher.Match(entry) {
+				counts[(sec-first)/size][group(entry)]++
+			}
+			if sec > reached {
+				reached = sec
+			}
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				hist.Partial = true
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			return Histogram{}, err
+		}
+
+		// Корзина полна, если кончилась до until, с запасом до now и, при остановке
+		// по бюджету, до последней прочитанной записи
+		closed := now.Add(-histogramSettle).Unix()
+		if until.Unix() < closed {
+			closed = until.Unix()
+		}
+		if hist.Partial && reached < closed {
+			closed = reached
+		}
+		s.histograms.store(key, scanFrom, size, counts[hist.Cached:], closed)
+	}
+
+	seen := make(map[string]bool)
+	hist.Buckets = make([]HistogramBucket, count)
+	for i, bucket := range counts {
+		total := 0
+		for name, n := range bucket {
+			total += n
+			if !seen[name] {
+				seen[name] = true
+				hist.Groups = append(hist.Groups, name)
+			}
+		}
+		hist.Buckets[i] = HistogramBucket{Time: time.Unix(first+int64(i)*size, 0), Total: total, Counts: bucket}
+	}
+	sortGroups(hist.Groups, opts.GroupBy)
+	return hist, nil
+}
+
+// histogramGroup — функция, относящая запись к группе по group_by
+func histogramGroup(groupBy string) (func(LogEntry) string, error) {
+	switch groupBy {
+	case "level":
+		return func(entry LogEntry) string { return EntrySeverity(entry).String() }, nil
+	case "unit":
+		return func(entry LogEntry) string { return entry.Unit }, nil
+	}
+	if name, ok := strings.CutPrefix(groupBy, entryFieldPrefix); ok && name != "" {
+		return func(entry LogEntry) string { return entry.Fields[name] }, nil
+	}
+	return nil, fmt.Errorf("invalid group_by %q, expected level, unit or field.<name>", groupBy)
+}
+
+// sortGroups упорядочивает уровни по серьёзности, остальные группы — по алфавиту
+func sortGroups(groups []string, groupBy string) {
+	if groupBy != "level" {
+		sort.Strings(groups)
+		return
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, _ := ParseSeverity(groups[i])
+		b, _ := ParseSeverity(groups[j])
+		return a < b
+	})
+}
+
+// histogramKey — ключ ряда в кэше: всё, от чего зависят счётчики корзин
+func histogramKey(units []string, filter LogFilter, groupBy string, size int64) string {
+	filter.Lines, filter.Since, filter.Until = 0, "", ""
+	filter.AfterCursor, filter.BeforeCursor = "", ""
+	data, _ := json.Marshal(struct {
+		Units   []string
+		Filter  LogFilter
+		GroupBy string
+		Size    int64
+	}{units, filter, groupBy, size})
+	return string(data)
+}
+
+// histogramCache хранит закрытые корзины гистограмм. Корзина после записи не меняется
+type histogramCache struct {
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	buckets map[int64]map[string]int // начало корзины (unix) → группа → число записей
+	used    time.Time
+}
+
+func newHistogramCache() *histogramCache {
+	return &histogramCache{series: make(map[string]*histogramSeries)}
+}
+
+// load заполняет counts корзинами из кэша подряд от first; возвращает их число
+func (c *histogramCache) load(key string, first, size int64, counts []map[string]int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	series, ok := c.series[key]
+	if !ok {
+		return 0
+	}
+	series.used = time.Now()
+	n := 0
+	for ; n < len(counts); n++ {
+		bucket, ok := series.buckets[first+int64(n)*size]
+		if !ok {
+			break
+		}
+		counts[n] = bucket
+	}
+	return n
+}
+
+// store кэширует корзины, начиная с start, которые закончились не позже closed (unix-время)
+func (c *histogramCache) store(key string, start, size int64, counts []map[string]int, closed int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	series, ok := c.series[key]
+	if !ok {
+		if len(c.series) >= maxHistogramSeries {
+			c.evict()
+		}
+		series = &histogramSeries{buckets: make(map[int64]map[string]int)}
+		c.series[key] = series
+	}
+	series.used = time.Now()
+	for i, bucket := range counts {
+		begin := start + int64(i)*size
+		if begin+size > closed {
+			break
+		}
+		series.buckets[begin] = bucket
+	}
+
+	// Ряд хранит не больше двух запросов максимальной длины, старые корзины удаляются
+	if excess := len(series.buckets) - 2*maxHistogramBuckets; excess > 0 {
+		begins := make([]int64, 0, len(series.buckets))
+		for begin := range series.buckets {
+			begins = append(begins, begin)
+		}
+		sort.Slice(begins, func(i, j int) bool { return begins[i] < begins[j] })
+		for _, begin := range begins[:excess] {
+			delete(series.buckets, begin)
+		}
+	}
+}
+
+// evict удаляет ряд, к которому дольше всех не обращались
+func (c *histogramCache) evict() {
+	var oldest string
+	for key, series := range c.series {
+		if oldest == "" || series.used.Before(c.series[oldest].used) {
+			oldest = key
+		}
+	}
+	delete(c.series, oldest)
+}
+
+// getHistogram - число записей по корзинам времени и уровням (group_by=level), юнитам или полям
+func (h *Handler) getHistogram(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	now := time.Now()
+
+	bucketParam := query.Get("bucket")
+	if bucketParam == "" {
+		bucketParam = histogramDefaultBucket
+	}
+	bucket, err := parseBucket(bucketParam)
+	if err != nil {
+		http.Error(w, "invalid bucket: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	since, until := now.Add(-histogramDefaultRange), now
+	if v := query.Get("since"); v != "" {
+		if since, err = config.ParseTime(v, now); err != nil {
+			http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		if until, err = config.ParseTime(v, now); err != nil {
+			http.Error(w, "invalid until: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if !since.Before(until) {
+		http.Error(w, "since must be before until", http.StatusBadRequest)
+		return
+	}
+	if since.After(now) {
+		http.Error(w, "since is in the future", http.StatusBadRequest)
+		return
+	}
+	if n := int64(until.Sub(since)/bucket) + 1; n > maxHistogramBuckets {
+		http.Error(w, fmt.Sprintf("too many buckets (%d, max %d): use a larger bucket or a shorter range", n, maxHistogramBuckets), http.StatusBadRequest)
+		return
+	}
+
+	groupBy := query.Get("group_by")
+	if groupBy == "" {
+		groupBy = "level"
+	}
+	if _, err := histogramGroup(groupBy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := LogFilter{
+		Level:    query.Get("level"),
+		MinLevel: query.Get("min_level"),
+		Search:   query.Get("search"),
+		Regex:    query.Get("regex"),
+		Query:    query.Get("q"),
+		Fields:   fieldParams(query),
+	}
+	if _, err := NewMatcher(filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	units, ok := h.requestUnits(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	hist, err := h.service.Histogram(ctx, units, filter, HistogramOptions{
+		Since:   since,
+		Until:   until,
+		Bucket:  bucket,
+		GroupBy: groupBy,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	hist.Units = units
+	hist.Bucket = bucketParam
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hist)
+}
+
+// parseBucket — ширина корзины: длительность Go ("30s", "5m", "1h") или дни ("1d"), кратная секунде
+func parseBucket(value string) (time.Duration, error) {
+	d, err := config.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < time.Second || d%time.Second != 0 {
+		return 0, fmt.Errorf("%q must be a whole number of seconds, at least 1s", value)
+	}
+	return d, nil
+}
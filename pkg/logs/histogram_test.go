@@ -0,0 +1,40 @@
+package logs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+func TestHistogramFutureRange(t *testing.T) {
+	s := NewService(config.Default())
+	now := time.Now()
+
+	hist, err := s.Histogram(context.Background(), []string{"picoclaw"}, LogFilter{}, HistogramOptions{
+		Since:   now.Add(time.Hour),
+		Until:   now.Add(2 * time.Hour),
+		Bucket:  time.Minute,
+		GroupBy: "level",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hist.Buckets) != 0 || hist.Scanned != 0 {
+		t.Errorf("future range: %d buckets, %d scanned; want an empty histogram", len(hist.Buckets), hist.Scanned)
+	}
+}
+
+func TestHistogramHandlerFutureSince(t *testing.T) {
+	h := NewHandler(NewService(config.Default()))
+
+	w := httptest.NewRecorder()
+	h.getHistogram(w, httptest.NewRequest(http.MethodGet, "/api/logs/histogram?since=2030-01-01T00:00:00Z&until=2030-01-01T01:00:00Z", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "future") {
+		t.Errorf("code = %d, body %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,214 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Вид записи для наследования уровня
+const (
+	kindOther        = iota
+	kindPrefixed     // строка разобрана парсером и содержит уровень, задаёт уровень процесса
+	kindContinuation // не разобрана, PRIORITY по умолчанию: продолжение многострочного сообщения
+)
+
+// decode разбирает одну строку вывода `journalctl -o json` без учёта соседних записей.
+// Сообщение разбирается парсером юнита записи, из него извлекаются поля (см. fieldExtractor);
+// unit — юнит для записей без _SYSTEMD_UNIT.
+// Если это не JSON (старый journalctl, -o cat), вся строка разбирается как сообщение
+func decode(line []byte, parsers *parserSet, unit string) (LogEntry, int, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return LogEntry{}, kindOther, false
+	}
+
+	var record map[string]json.RawMessage
+	if line[0] != '{' || json.Unmarshal(line, &record) != nil {
+		entry := LogEntry{Timestamp: time.Now(), Message: string(line), Unit: unit, Priority: 6}
+		kind := applyParser(&entry, parsers.get(unit), true)
+		entry.Fields = parsers.fields.extract(entry.Message, entry.Fields)
+		return entry, kind, true
+	}
+
+	entry := LogEntry{
+		Message:          journalString(record["MESSAGE"]),
+		Cursor:           journalString(record["__CURSOR"]),
+		Unit:             strings.TrimSuffix(journalString(record["_SYSTEMD_UNIT"]), ".service"),
+		SyslogIdentifier: journalString(record["SYSLOG_IDENTIFIER"]),
+		BootID:           journalString(record["_BOOT_ID"]),
+		Priority:         6,
+	}
+	if entry.Unit == "" {
+		entry.Unit = unit
+	}
+	if usec, err := strconv.ParseInt(journalString(record["__REALTIME_TIMESTAMP"]), 10, 64); err == nil {
+		entry.Timestamp = time.UnixMicro(usec)
+	} else {
+		entry.Timestamp = time.Now()
+	}
+	entry.PID, _ = strconv.Atoi(journalString(record["_PID"]))
+	if prio, err := strconv.Atoi(journalString(record["PRIORITY"])); err == nil && prio >= 0 && prio < len(prioritySeverities) {
+		entry.Priority = prio
+	}
+
+	kind := applyParser(&entry, parsers.get(entry.Unit), false)
+	entry.Fields = parsers.fields.extract(entry.Message, entry.Fields)
+	return entry, kind, true
+}
+
+// applyParser разбирает сообщение записи и определяет её уровень.
+// text — запись без метаданных журнала: время и PRIORITY берутся из строки
+func applyParser(entry *LogEntry, parser Parser, text bool) int {
+	parsed, ok := parser.Parse(entry.Message)
+	if !ok {
+		entry.Level = PrioritySeverity(entry.Priority).String()
+		if entry.Priority == 6 {
+			// picoclaw пишет всё с одним PRIORITY — уровень продолжения берётся из начала сообщения
+			return kindContinuation
+		}
+		return kindOther
+	}
+
+	entry.Message = parsed.Message
+	entry.Fields = parsed.Fields
+	if text && !parsed.Time.IsZero() {
+		entry.Timestamp = parsed.Time
+	}
+	if parsed.Level == "" {
+		entry.Level = PrioritySeverity(entry.Priority).String()
+		return kindOther
+	}
+	entry.Level = normalizeLevel(parsed.Level, PrioritySeverity(entry.Priority))
+	if text {
+		entry.Priority = severityAliases[entry.Level].Priority()
+	}
+	return kindPrefixed
+}
+
+// entryParser разбирает записи в порядке журнала (от старых к новым).
+// Продолжения наследуют уровень предыдущей записи с префиксом того же процесса
+type entryParser struct {
+	parsers   *parserSet
+	unit      string
+	lastLevel map[int]string // PID → уровень последней записи с префиксом
+}
+
+func newEntryParser(parsers *parserSet, unit string) *entryParser {
+	return &entryParser{parsers: parsers, unit: unit, lastLevel: make(map[int]string)}
+}
+
+func (p *entryParser) parse(line []byte) (LogEntry, bool) {
+	entry, kind, ok := decode(line, p.parsers, p.unit)
+	if !ok {
+		return entry, false
+	}
+	switch kind {
+	case kindPrefixed:
+		p.lastLevel[entry.PID] = entry.Level
+	case kindContinuation:
+		if level, ok := p.lastLevel[entry.PID]; ok {
+			entry.Level = level
+		}
+	}
+	return entry, true
+}
+
+// maxPendingEntries — сколько записей backwardParser держит в ожидании начала сообщения
+const maxPendingEntries = 1000
+
+// backwardParser разбирает записи при чтении с конца (--reverse). Продолжение приходит
+// раньше своего начала, поэтому записи задерживаются, пока не станет известен уровень;
+// порядок выдачи совпадает с порядком чтения
+type backwardParser struct {
+	parsers *parserSet
+	unit    string
+	queue   []*pendingEntry
+	pending map[int][]*pendingEntry // PID → продолжения без уровня
+}
+
+type pendingEntry struct {
+	entry    LogEntry
+	resolved bool
+}
+
+func newBackwardParser(parsers *parserSet, unit string) *backwardParser {
+	return &backwardParser{parsers: parsers, unit: unit, pending: make(map[int][]*pendingEntry)}
+}
+
+// push добавляет следующую (более старую) строку и возвращает записи, готовые к выдаче
+func (p *backwardParser) push(line []byte) []LogEntry {
+	entry, kind, ok := decode(line, p.parsers, p.unit)
+	if !ok {
+		return nil
+	}
+
+	item := &pendingEntry{entry: entry, resolved: kind != kindContinuation}
+	p.queue = append(p.queue, item)
+	switch kind {
+	case kindContinuation:
+		p.pending[entry.PID] = append(p.pending[entry.PID], item)
+	case kindPrefixed:
+		for _, cont := range p.pending[entry.PID] {
+			cont.entry.Level = entry.Level
+			cont.resolved = true
+		}
+		delete(p.pending, entry.PID)
+	}
+
+	if len(p.queue) > maxPendingEntries {
+		// Начала так и нет — отдаём с уровнем по PRIORITY
+		p.queue[0].resolved = true
+	}
+
+	var ready []LogEntry
+	for len(p.queue) > 0 && p.queue[0].resolved {
+		ready = append(ready, p.queue[0].entry)
+		p.queue = p.queue[1:]
+	}
+	return ready
+}
+
+// flush отдаёт оставшиеся записи в конце журнала
+func (p *backwardParser) flush() []LogEntry {
+	ready := make([]LogEntry, 0, len(p.queue))
+	for _, item := range p.queue {
+		ready = append(ready, item.entry)
+	}
+	p.queue = nil
+	p.pending = make(map[int][]*pendingEntry)
+	return ready
+}
+
+// normalizeLevel приводит уровень из префикса к канонической шкале (WARNING → WARN, ERR → ERROR).
+// Неизвестный уровень заменяется fallback
+func normalizeLevel(level string, fallback Severity) string {
+	if sev, err := ParseSeverity(level); err == nil {
+		return sev.String()
+	}
+	return fallback.String()
+}
+
+// journalString декодирует поле журнала: строку или, для не-UTF-8 данных, массив байтов
+func journalString(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	switch raw[0] {
+	case '"':
+		var s string
+		json.Unmarshal(raw, &s)
+		return s
+	case '[':
+		var b []int
+		json.Unmarshal(raw, &b)
+		buf := make([]byte, len(b))
+		for i, c := range b {
+			buf[i] = byte(c)
+		}
+		return string(buf)
+	}
+	return ""
+}
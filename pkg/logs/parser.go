@@ -0,0 +1,297 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// Parser разбирает текст одной строки лога (MESSAGE записи журнала)
+type Parser interface {
+	// Parse возвращает разобранную строку; false — строка не в формате парсера
+	// (например, продолжение многострочного сообщения)
+	Parse(line string) (ParsedLine, bool)
+}
+
+// ParsedLine — результат разбора строки
+type ParsedLine struct {
+	Time    time.Time // нулевое — в строке нет времени
+	Level   string    // как в строке, "" — нет уровня (берётся из PRIORITY)
+	Message string
+	Fields  map[string]string // прочие поля строки (ключи JSON и logfmt, группы regex-парсера)
+}
+
+// Встроенные парсеры
+var builtinParsers = map[string]Parser{
+	"picoclaw": picoclawParser{},
+	"json":     jsonParser{},
+	"logfmt":   logfmtParser{},
+	"raw":      rawParser{},
+}
+
+// picoclawPattern — префикс строк picoclaw: YYYY/MM/DD HH:MM:SS [timestamp] [LEVEL] ...
+var picoclawPattern = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[.*?\] \[([A-Z]+)\] (.*)`)
+
+// picoclawComponent — компонент picoclaw в начале сообщения: [agent] ...
+var picoclawComponent = regexp.MustCompile(`^\[([\w.-]+)\] `)
+
+// picoclawParser — формат picoclaw; строки без префикса — продолжения.
+// Компонент сообщения становится полем component
+type picoclawParser struct{}
+
+func (picoclawParser) Parse(line string) (ParsedLine, bool) {
+	matches := picoclawPattern.FindStringSubmatch(line)
+	if matches == nil {
+		return ParsedLine{}, false
+	}
+	t, _ := time.ParseInLocation("2006/01/02 15:04:05", matches[1], time.Local)
+	parsed := ParsedLine{Time: t, Level: matches[2], Message: matches[3]}
+	if component := picoclawComponent.FindStringSubmatch(parsed.Message); component != nil {
+		parsed.Fields = map[string]string{"component": component[1]}
+	}
+	return parsed, true
+}
+
+// Ключи JSON и logfmt, в которых ищутся время, уровень и сообщение
+var (
+	timeKeys    = []string{"time", "ts", "timestamp", "@timestamp", "t"}
+	levelKeys   = []string{"level", "lvl", "severity", "@level", "loglevel"}
+	messageKeys = []string{"msg", "message", "@message"}
+)
+
+// jsonParser — строка-объект JSON (zap, zerolog, slog.JSONHandler, logrus)
+type jsonParser struct{}
+
+func (jsonParser) Parse(line string) (ParsedLine, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return ParsedLine{}, false
+	}
+	var record map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber() // без потери точности больших id
+	if err := decoder.Decode(&record); err != nil || decoder.InputOffset() != int64(len(line)) {
+		return ParsedLine{}, false
+	}
+
+	values := make(map[string]string, len(record))
+	flattenJSON("", record, func(key, value string) { values[key] = value })
+	return structuredLine(values, line), true
+}
+
+// logfmtParser — пары key=value (logfmt, slog.TextHandler). Строка должна содержать msg или level
+type logfmtParser struct{}
+
+func (logfmtParser) Parse(line string) (ParsedLine, bool) {
+	values, ok := parseLogfmt(line)
+	if !ok || (lookup(values, messageKeys) == "" && lookup(values, levelKeys) == "") {
+		return ParsedLine{}, false
+	}
+	return structuredLine(values, line), true
+}
+
+// parseLogfmt разбирает key=value и key="quoted value"; false — строка не logfmt
+func parseLogfmt(line string) (map[string]string, bool) {
+	values := make(map[string]string)
+	i := 0
+	for i < len(line) {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i >= len(line) {
+			break
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		if i >= len(line) || line[i] != '=' || i == start {
+			return nil, false
+		}
+		key := line[start:i]
+		i++
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			var sb strings.Builder
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+				sb.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return nil, false
+			}
+			i++
+			value = sb.String()
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			value = line[start:i]
+		}
+		values[key] = value
+	}
+	return values, len(values) > 0
+}
+
+// structuredLine собирает ParsedLine из полей JSON или logfmt; остальные ключи — Fields.
+// Без поля сообщения сообщением остаётся вся строка
+func structuredLine(values map[string]string, line string) ParsedLine {
+	parsed := ParsedLine{Level: take(values, levelKeys), Message: take(values, messageKeys)}
+	if parsed.Message == "" {
+		parsed.Message = line
+	}
+	if ts := take(values, timeKeys); ts != "" {
+		parsed.Time = parseLineTime(ts, "")
+	}
+	if len(values) > 0 {
+		parsed.Fields = values
+	}
+	return parsed
+}
+
+func lookup(values map[string]string, keys []string) string {
+	for _, k := range keys {
+		if v, ok := values[k]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// take — lookup, удаляющий найденный ключ
+func take(values map[string]string, keys []string) string {
+	for _, k := range keys {
+		if v, ok := values[k]; ok {
+			delete(values, k)
+			return v
+		}
+	}
+	return ""
+}
+
+// rawParser — строка как есть, уровень по PRIORITY
+type rawParser struct{}
+
+func (rawParser) Parse(line string) (ParsedLine, bool) {
+	return ParsedLine{Message: line}, true
+}
+
+// regexParser — пользовательский формат (logs.parsers). Именованные группы:
+// message (или msg), level, time; time разбирается по timeFormat. Прочие группы — поля
+type regexParser struct {
+	re         *regexp.Regexp
+	timeFormat string
+}
+
+func newRegexParser(cfg config.LogParser) (*regexParser, error) {
+	re, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("parser %s: %w", cfg.Name, err)
+	}
+	return &regexParser{re: re, timeFormat: cfg.TimeFormat}, nil
+}
+
+func (p *regexParser) Parse(line string) (ParsedLine, bool) {
+	matches := p.re.FindStringSubmatch(line)
+	if matches == nil {
+		return ParsedLine{}, false
+	}
+	parsed := ParsedLine{Message: line}
+	for i, name := range p.re.SubexpNames() {
+		switch name {
+		case "message", "msg":
+			parsed.Message = matches[i]
+		case "level":
+			parsed.Level = matches[i]
+		case "time":
+			parsed.Time = parseLineTime(matches[i], p.timeFormat)
+		case "":
+		default:
+			if matches[i] != "" {
+				if parsed.Fields == nil {
+					parsed.Fields = make(map[string]string)
+				}
+				parsed.Fields[name] = matches[i]
+			}
+		}
+	}
+	return parsed, true
+}
+
+// parseLineTime разбирает время из строки лога: по layout, если задан, иначе RFC3339
+// или unix-время в секундах. Ошибка даёт нулевое время
+func parseLineTime(value, layout string) time.Time {
+	if layout != "" {
+		t, _ := time.ParseInLocation(layout, value, time.Local)
+		return t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t
+	}
+	if sec, err := strconv.ParseFloat(value, 64); err == nil && sec > 0 {
+		return time.Unix(0, int64(sec*float64(time.Second)))
+	}
+	return time.Time{}
+}
+
+// parserSet — парсеры по юнитам (logs.sources), парсер по умолчанию (logs.parser)
+// и правила извлечения полей (logs.fields)
+type parserSet struct {
+	byUnit   map[string]Parser
+	fallback Parser
+	fields   *fieldExtractor
+}
+
+// newParserSet собирает парсеры из конфигурации; имена проверены в config.Validate
+func newParserSet(cfg config.LogsConfig) (*parserSet, error) {
+	named := make(map[string]Parser, len(builtinParsers)+len(cfg.Parsers))
+	for name, p := range builtinParsers {
+		named[name] = p
+	}
+	for _, pc := range cfg.Parsers {
+		p, err := newRegexParser(pc)
+		if err != nil {
+			return nil, err
+		}
+		named[pc.Name] = p
+	}
+
+	get := func(name string) (Parser, error) {
+		if p, ok := named[name]; ok {
+			return p, nil
+		}
+		return nil, fmt.Errorf("unknown parser %q", name)
+	}
+
+	fields, err := newFieldExtractor(cfg.Fields)
+	if err != nil {
+		return nil, err
+	}
+	ps := &parserSet{byUnit: make(map[string]Parser, len(cfg.Sources)), fields: fields}
+	if ps.fallback, err = get(cfg.Parser); err != nil {
+		return nil, err
+	}
+	for unit, name := range cfg.Sources {
+		if ps.byUnit[unit], err = get(name); err != nil {
+			return nil, fmt.Errorf("source %s: %w", unit, err)
+		}
+	}
+	return ps, nil
+}
+
+func (ps *parserSet) get(unit string) Parser {
+	if p, ok := ps.byUnit[unit]; ok {
+		return p
+	}
+	return ps.fallback
+}
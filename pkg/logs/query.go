@@ -0,0 +1,224 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor — journalctl не смог найти позицию по курсору
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// LogPage — страница записей с курсорами для соседних страниц
+type LogPage struct {
+	Entries []LogEntry
+	// Курсоры границ просмотренного участка журнала: по ним следующая страница
+	// продолжает чтение, не просматривая записи повторно. Если записей нет,
+	// повторяют курсоры запроса
+	BeforeCursor string
+	AfterCursor  string
+	// HasMore — за страницей в направлении чтения есть ещё записи:
+	// более старые, если AfterCursor не задан, иначе более новые
+	HasMore bool
+	// Scanned — сколько записей журнала просмотрено ради страницы
+	Scanned int
+	// Partial — чтение остановлено по бюджету времени (logs.query_budget)
+	// раньше, чем набралось filter.Lines совпадений
+	Partial bool
+}
+
+// Query возвращает filter.Lines записей, подходящих под фильтры (0 — без ограничения).
+// Без курсоров — последние совпадения; с AfterCursor — следующие за курсором;
+// с BeforeCursor — предшествующие ему. Журнал читается потоком от курсора,
+// пока не наберётся нужное число совпадений или не истечёт бюджет времени.
+// Записи нескольких юнитов journalctl сливает в порядке времени; курсоры общие для всех.
+// Записи идут от старых к новым
+func (s *Service) Query(ctx context.Context, units []string, filter LogFilter) (LogPage, error) {
+	if len(units) == 0 {
+		return LogPage{}, errors.New("no units")
+	}
+	if filter.AfterCursor != "" && filter.BeforeCursor != "" {
+		return LogPage{}, errors.New("after_cursor and before_cursor are mutually exclusive")
+	}
+	matcher, err := NewMatcher(filter)
+	if err != nil {
+		return LogPage{}, err
+	}
+
+	args := journalArgs(units, filter)
+	forward := filter.AfterCursor != ""
+	if forward {
+		args = append(args, "--after-cursor="+filter.AfterCursor)
+	} else {
+		// Читаем с конца; --cursor включает саму запись курсора, её пропускаем
+		args = append(args, "--reverse")
+		if filter.BeforeCursor != "" {
+			args = append(args, "--cursor="+filter.BeforeCursor)
+		}
+	}
+
+	var deadline time.Time
+	if s.queryBudget > 0 {
+		deadline = time.Now().Add(s.queryBudget)
+	}
+
+	page := LogPage{}
+	var matched []LogEntry // в порядке чтения
+	var firstCursor, lastCursor string
+	full := false // набрано filter.Lines совпадений и найдено ещё одно
+
+	// handle обрабатывает запись в порядке чтения; false — хватит читать
+	handle := func(entry LogEntry) bool {
+		if !forward && page.Scanned == 0 && filter.BeforeCursor != "" && entry.Cursor == filter.BeforeCursor {
+			return true
+		}
+		page.Scanned++
+		if firstCursor == "" {
+			firstCursor = entry.Cursor
+		}
+		lastCursor = entry.Cursor
+
+		if matcher.Match(entry) {
+			if filter.Lines > 0 && len(matched) == filter.Lines {
+				full = true
+				return false
+			}
+			matched = append(matched, entry)
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			page.Partial = true
+			return false
+		}
+		return true
+	}
+
+	forwardParser := newEntryParser(s.parsers, soleUnit(units))
+	backwardParser := newBackwardParser(s.parsers, soleUnit(units))
+	err = readJournal(ctx, args, func(line []byte) bool {
+		if forward {
+			entry, ok := forwardParser.parse(line)
+			return !ok || handle(entry)
+		}
+		for _, entry := range backwardParser.push(line) {
+			if !handle(entry) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		if filter.AfterCursor != "" || filter.BeforeCursor != "" {
+			if strings.Contains(err.Error(), "cursor") {
+				return LogPage{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+			}
+		}
+		return LogPage{}, err
+	}
+	if !forward && !full && !page.Partial {
+		for _, entry := range backwardParser.flush() {
+			if !handle(entry) {
+				break
+			}
+		}
+	}
+
+	page.HasMore = full || page.Partial
+	page.BeforeCursor, page.AfterCursor = filter.BeforeCursor, filter.AfterCursor
+	if page.Scanned > 0 {
+		// Если страница заполнилась, граница — последнее взятое совпадение:
+		// следующая страница начнётся сразу за ним
+		boundary := lastCursor
+		if full {
+			boundary = matched[len(matched)-1].Cursor
+		}
+		if forward {
+			page.BeforeCursor, page.AfterCursor = firstCursor, boundary
+		} else {
+			page.BeforeCursor, page.AfterCursor = boundary, firstCursor
+		}
+	}
+
+	if !forward {
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+	page.Entries = matched
+	return page, nil
+}
+
+// journalArgs — аргументы journalctl для чтения юнитов в JSON с ограничениями по времени из фильтра
+func journalArgs(units []string, filter LogFilter) []string {
+	var args []string
+	for _, unit := range units {
+		args = append(args, "-u", unit)
+	}
+	args = append(args, "-o", "json", "--no-pager")
+	if filter.Since != "" {
+		args = append(args, "--since", parseRelativeTime(filter.Since))
+	}
+	if filter.Until != "" {
+		args = append(args, "--until", parseRelativeTime(filter.Until))
+	}
+	return args
+}
+
+// soleUnit — юнит для записей без _SYSTEMD_UNIT (разобранных как текст), если он однозначен
+func soleUnit(units []string) string {
+	if len(units) == 1 {
+		return units[0]
+	}
+	return ""
+}
+
+// readJournal запускает journalctl и передаёт строки вывода в fn, пока она возвращает true.
+// После досрочной остановки процесс завершается, его ошибка не возвращается
+func readJournal(ctx context.Context, args []string, fn func(line []byte) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("stdout pipe error: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("journalctl error: %w", err)
+	}
+
+	stopped := false
+	scanner := newLineScanner(stdout)
+	for scanner.Scan() {
+		if !fn(scanner.Bytes()) {
+			stopped = true
+			break
+		}
+	}
+	// Если чтение прервано (в том числе ошибкой — например, запись длиннее maxRecordSize),
+	// journalctl останавливаем: иначе Wait ждёт его завершения, а с -f — бесконечно
+	scanErr := scanner.Err()
+	if stopped || scanErr != nil {
+		cancel()
+	}
+	err = cmd.Wait()
+
+	if stopped {
+		return nil
+	}
+	if scanErr != nil {
+		return fmt.Errorf("journalctl output: %w", scanErr)
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if err != nil {
+		return fmt.Errorf("journalctl error: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
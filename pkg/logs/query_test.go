@@ -0,0 +1,116 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// longRecordJournalctl выдаёт запись длиннее maxRecordSize и не завершается
+const longRecordJournalctl = `#!/bin/sh
+head -c 1100000 /dev/zero | tr '\0' a
+echo
+exec sleep 30
+`
+
+func TestReadJournalTooLongRecord(t *testing.T) {
+	useFakeJournalctl(t, longRecordJournalctl)
+	s := NewService(config.Default())
+
+	tests := map[string]func() error{
+		"readJournal": func() error {
+			return readJournal(context.Background(), nil, func(line []byte) bool { return true })
+		},
+		"FollowUnit": func() error {
+			return s.FollowUnit(context.Background(), "picoclaw", 0, func(LogEntry) {})
+		},
+	}
+	for name, read := range tests {
+		t.Run(name, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() { done <- read() }()
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, bufio.ErrTooLong) {
+					t.Errorf("err = %v, want bufio.ErrTooLong", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("journalctl was not stopped after a scanner error")
+			}
+		})
+	}
+}
+
+func TestQueryCursors(t *testing.T) {
+	useFakeJournalctl(t, fixedJournalctl)
+	s := NewService(config.Default())
+
+	tests := []struct {
+		name    string
+		filter  LogFilter
+		want    string // курсоры записей страницы
+		hasMore bool
+		before  string
+		after   string
+	}{
+		{"newest", LogFilter{Lines: 2}, "c4 c5", true, "c4", "c5"},
+		{"whole journal", LogFilter{Lines: 5}, "c1 c2 c3 c4 c5", false, "c1", "c5"},
+		{"more lines than entries", LogFilter{Lines: 10}, "c1 c2 c3 c4 c5", false, "c1", "c5"},
+		// Запись самого курсора в страницу не входит
+		{"before", LogFilter{Lines: 2, BeforeCursor: "c5"}, "c3 c4", true, "c3", "c4"},
+		{"before to the start", LogFilter{Lines: 2, BeforeCursor: "c3"}, "c1 c2", false, "c1", "c2"},
+		{"before the first entry", LogFilter{Lines: 2, BeforeCursor: "c1"}, "", false, "c1", ""},
+		{"after", LogFilter{Lines: 2, AfterCursor: "c1"}, "c2 c3", true, "c2", "c3"},
+		{"after to the end", LogFilter{Lines: 2, AfterCursor: "c3"}, "c4 c5", false, "c4", "c5"},
+		{"after the last entry", LogFilter{Lines: 2, AfterCursor: "c5"}, "", false, "", "c5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := s.Query(context.Background(), []string{"picoclaw"}, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pageCursors(page); got != tt.want {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+			if page.HasMore != tt.hasMore {
+				t.Errorf("HasMore = %v, want %v", page.HasMore, tt.hasMore)
+			}
+			if page.BeforeCursor != tt.before || page.AfterCursor != tt.after {
+				t.Errorf("cursors = %q, %q; want %q, %q", page.BeforeCursor, page.AfterCursor, tt.before, tt.after)
+			}
+		})
+	}
+
+	// Листание назад по before_cursor проходит журнал без пропусков и повторов
+	var pages []string
+	filter := LogFilter{Lines: 2}
+	for {
+		page, err := s.Query(context.Background(), []string{"picoclaw"}, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, pageCursors(page))
+		if !page.HasMore {
+			break
+		}
+		filter.BeforeCursor = page.BeforeCursor
+	}
+	if got := strings.Join(pages, " | "); got != "c4 c5 | c2 c3 | c1" {
+		t.Errorf("pages = %s", got)
+	}
+}
+
+func pageCursors(page LogPage) string {
+	var cursors []string
+	for _, entry := range page.Entries {
+		cursors = append(cursors, entry.Cursor)
+	}
+	return strings.Join(cursors, " ")
+}
@@ -1,59 +1,73 @@
 package logs
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"log"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
 )
 
+// maxRecordSize — предел длины одной записи `journalctl -o json`
+const maxRecordSize = 1 << 20
+
 type Service struct {
-	unit string // systemd unit name (например, "picoclaw")
+	unit  string   // systemd unit name (например, "picoclaw")
+	units []string // юниты, логи которых можно читать (logs.units), unit — первый
+
+	parsers    *parserSet      // разбор сообщений по юнитам (logs.parser, logs.sources)
+	histograms *histogramCache // закрытые корзины гистограмм (см. Histogram)
+
+	// Общие follower'ы юнитов (см. Subscribe)
+	backlog          int
+	subscriberBuffer int
+	queryBudget      time.Duration
+	followMu         sync.Mutex
+	followers        map[string]*follower
+	dropped          uint64
 }
 
-func NewService(unit string) *Service {
+func NewService(cfg *config.Config) *Service {
+	parsers, err := newParserSet(cfg.Logs)
+	if err != nil {
+		// Конфигурация уже проверена, сюда попадаем только при ошибке в ней самой
+		log.Printf("⚠️  Logs: %v, using the picoclaw parser", err)
+		parsers = &parserSet{fallback: picoclawParser{}, fields: &fieldExtractor{}}
+	}
+
 	return &Service{
-		unit: unit,
+		parsers:          parsers,
+		histograms:       newHistogramCache(),
+		unit:             cfg.Logs.Unit,
+		units:            cfg.Logs.AllowedUnits(),
+		backlog:          cfg.Logs.Backlog,
+		subscriberBuffer: cfg.Logs.SubscriberBuffer,
+		queryBudget:      cfg.Logs.QueryBudget.Std(),
+		followers:        make(map[string]*follower),
 	}
 }
 
 // GetLogs - получает логи из journalctl
 func (s *Service) GetLogs(ctx context.Context, filter LogFilter) ([]LogEntry, error) {
-	// Базовые параметры
-	args := []string{
-		"-u", s.unit,
-		"-o", "cat",         // Простой вывод
-		"--no-pager",        // Не использовать пейджер
-	}
-
-	// Фильтр по времени
-	if filter.Since != "" {
-		sinceTime := parseRelativeTime(filter.Since)
-		args = append(args, "--since", sinceTime)
-	}
-
-	// Количество строк (если указано)
-	if filter.Lines > 0 {
-		args = append(args, "-n", fmt.Sprintf("%d", filter.Lines))
-	}
-
-	// Выполняем journalctl
-	cmd := exec.CommandContext(ctx, "journalctl", args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	return s.GetUnitLogs(ctx, s.unit, filter)
+}
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("journalctl error: %w: %s", err, stderr.String())
+// GetUnitLogs - логи указанного юнита
+func (s *Service) GetUnitLogs(ctx context.Context, unit string, filter LogFilter) ([]LogEntry, error) {
+	page, err := s.Query(ctx, []string{unit}, filter)
+	if err != nil {
+		return nil, err
 	}
-
-	// Парсим логи
-	entries := s.parseLogs(stdout.String(), filter)
-
-	return entries, nil
+	return page.Entries, nil
 }
 
 // parseRelativeTime конвертирует относительное время в формат journalctl
@@ -82,199 +96,62 @@ func parseRelativeTime(since string) string {
 	}
 }
 
-// parseLogs парсит вывод journalctl и фильтрует по уровню/поиску
-func (s *Service) parseLogs(output string, filter LogFilter) []LogEntry {
-	lines := strings.Split(output, "\n")
-	var entries []LogEntry
-
-	// Регулярка для парсинга строки лога
-	// Формат: YYYY/MM/DD HH:MM:SS [timestamp] [LEVEL] ...
-	logPattern := regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[.*?\] \[([A-Z]+)\] (.*)`)
-
-	var currentEntry *LogEntry
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		matches := logPattern.FindStringSubmatch(line)
-		if matches == nil {
-			// Если есть текущая запись, добавляем строку к ней
-			if currentEntry != nil {
-				if currentEntry.Message != "" {
-					currentEntry.Message += "\n" + line
-				} else {
-					currentEntry.Message = line
-				}
-			}
-			// Иначе пропускаем строку (это мусор в начале вывода)
-			continue
-		}
-
-		// Если есть незавершенная запись, сохраняем её
-		if currentEntry != nil {
-			// Фильтр по уровню
-			if filter.Level == "" || currentEntry.Level == filter.Level {
-				// Фильтр по поиску
-				if filter.Search == "" || strings.Contains(strings.ToLower(currentEntry.Message), strings.ToLower(filter.Search)) ||
-					strings.Contains(strings.ToLower(currentEntry.Level), strings.ToLower(filter.Search)) {
-					entries = append(entries, *currentEntry)
-				}
-			}
-		}
-
-		// Парсим timestamp
-		timestamp, err := time.Parse("2006/01/02 15:04:05", matches[1])
-		if err != nil {
-			timestamp = time.Now()
-		}
-
-		level := matches[2]
-		message := matches[3]
-
-		// Начинаем новую запись
-		currentEntry = &LogEntry{
-			Timestamp: timestamp,
-			Level:     level,
-			Message:   message,
-		}
-	}
+// Unit возвращает юнит по умолчанию
+func (s *Service) Unit() string {
+	return s.unit
+}
 
-	// Сохраняем последнюю запись
-	if currentEntry != nil {
-		// Фильтр по уровню
-		if filter.Level == "" || currentEntry.Level == filter.Level {
-			// Фильтр по поиску
-			if filter.Search == "" || strings.Contains(strings.ToLower(currentEntry.Message), strings.ToLower(filter.Search)) ||
-				strings.Contains(strings.ToLower(currentEntry.Level), strings.ToLower(filter.Search)) {
-				entries = append(entries, *currentEntry)
-			}
-		}
-	}
+// Units возвращает юниты, логи которых можно читать
+func (s *Service) Units() []string {
+	return append([]string(nil), s.units...)
+}
 
-	// Сортируем по времени (старые первые)
-	for i := 0; i < len(entries); i++ {
-		for j := i + 1; j < len(entries); j++ {
-			if entries[i].Timestamp.After(entries[j].Timestamp) {
-				entries[i], entries[j] = entries[j], entries[i]
-			}
+// Allowed сообщает, можно ли читать логи юнита
+func (s *Service) Allowed(unit string) bool {
+	for _, u := range s.units {
+		if u == unit {
+			return true
 		}
 	}
-
-	return entries
+	return false
 }
 
-// FollowLogs - открывает поток логов (tail -f)
+// FollowLogs - открывает поток логов (tail -f).
+// Каждый вызов запускает свой journalctl; для нескольких читателей используйте Subscribe
 func (s *Service) FollowLogs(ctx context.Context, callback func(LogEntry)) error {
-	args := []string{
-		"-u", s.unit,
-		"-o", "cat",
-		"--no-pager",
-		"-f", // follow
-	}
-
-	cmd := exec.CommandContext(ctx, "journalctl", args...)
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("stdout pipe error: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("start error: %w", err)
-	}
-
-	// Читаем построчно и объединяем многострочные сообщения
-	buf := make([]byte, 4096)
-	var currentEntry *LogEntry
-
-	for {
-		select {
-		case <-ctx.Done():
-			cmd.Process.Kill()
-			return ctx.Err()
-		default:
-			n, err := stdout.Read(buf)
-			if err != nil {
-				// Если есть незавершенная запись, отправляем её
-				if currentEntry != nil {
-					callback(*currentEntry)
-				}
-				return err
-			}
-			if n > 0 {
-				chunk := string(buf[:n])
-				// Разбиваем на строки по переносу
-				lines := strings.Split(chunk, "\n")
-
-				for _, line := range lines {
-					line = strings.TrimSpace(line)
-					if line == "" {
-						continue
-					}
-
-					entry := s.parseLogLine(line)
-
-					// Если строка не совпала с форматом (continuation line)
-					if entry == nil {
-						// Добавляем к текущей записи
-						if currentEntry != nil {
-							if currentEntry.Message != "" {
-								currentEntry.Message += " " + line
-							} else {
-								currentEntry.Message = line
-							}
-						}
-						continue
-					}
-
-					// Если это продолжение предыдущей записи (тот же timestamp)
-					if currentEntry != nil &&
-						entry.Timestamp.Equal(currentEntry.Timestamp) &&
-						entry.Level == currentEntry.Level {
-						// Добавляем к текущей записи
-						if currentEntry.Message != "" {
-							currentEntry.Message += " " + entry.Message
-						} else {
-							currentEntry.Message = entry.Message
-						}
-					} else {
-						// Отправляем предыдущую запись
-						if currentEntry != nil {
-							callback(*currentEntry)
-						}
-						// Начинаем новую запись
-						currentEntry = entry
-					}
-				}
-			}
-		}
-	}
+	return s.FollowUnit(ctx, s.unit, 10, callback)
 }
 
-// parseLogLine парсит одну строку лога
-// Возвращает nil если строка не совпадает с форматом лога (это продолжение предыдущей записи)
-func (s *Service) parseLogLine(line string) *LogEntry {
-	logPattern := regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[.*?\] \[([A-Z]+)\] (.*)`)
-	matches := logPattern.FindStringSubmatch(line)
-
-	if matches == nil {
-		// Не совпало с форматом лога - это continuation line
-		return nil
-	}
+// FollowUnit - поток логов указанного юнита, начиная с lines последних записей
+func (s *Service) FollowUnit(ctx context.Context, unit string, lines int, callback func(LogEntry)) error {
+	return s.follow(ctx, unit, []string{"-n", strconv.Itoa(lines)}, callback)
+}
 
-	timestamp, err := time.Parse("2006/01/02 15:04:05", matches[1])
+// follow читает `journalctl -f` юнита с позиции position (-n или --after-cursor).
+// Возвращает io.EOF, если journalctl завершился сам
+func (s *Service) follow(ctx context.Context, unit string, position []string, callback func(LogEntry)) error {
+	args := append([]string{"-u", unit, "-o", "json", "--no-pager"}, position...)
+	args = append(args, "-f") // follow
+
+	// Каждая строка вывода — одна запись журнала
+	parser := newEntryParser(s.parsers, unit)
+	err := readJournal(ctx, args, func(line []byte) bool {
+		if entry, ok := parser.parse(line); ok {
+			callback(entry)
+		}
+		return true
+	})
 	if err != nil {
-		timestamp = time.Now()
+		return err
 	}
+	return io.EOF
+}
 
-	return &LogEntry{
-		Timestamp: timestamp,
-		Level:     matches[2],
-		Message:   matches[3],
-	}
+// newLineScanner читает вывод journalctl построчно; записи журнала бывают длинными
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	return scanner
 }
 
 // GetLogUnits возвращает список доступных systemd юнитов
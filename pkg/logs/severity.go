@@ -0,0 +1,98 @@
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Severity — каноническая шкала уровней: чем больше, тем строже.
+// К ней приводятся уровни picoclaw, journald PRIORITY и распространённые синонимы
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+	SeverityFatal
+)
+
+var severityNames = [...]string{"", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// severityAliases — имена уровней в верхнем регистре
+var severityAliases = map[string]Severity{
+	"TRACE":       SeverityDebug,
+	"DEBUG":       SeverityDebug,
+	"DBG":         SeverityDebug,
+	"INFO":        SeverityInfo,
+	"INF":         SeverityInfo,
+	"NOTICE":      SeverityInfo,
+	"INFORMATION": SeverityInfo,
+	"WARN":        SeverityWarn,
+	"WARNING":     SeverityWarn,
+	"WRN":         SeverityWarn,
+	"ERROR":       SeverityError,
+	"ERR":         SeverityError,
+	"ERRO":        SeverityError,
+	"FATAL":       SeverityFatal,
+	"PANIC":       SeverityFatal,
+	"CRIT":        SeverityFatal,
+	"CRITICAL":    SeverityFatal,
+	"ALERT":       SeverityFatal,
+	"EMERG":       SeverityFatal,
+}
+
+// Уровни по journald PRIORITY (syslog): 0–2 FATAL, 3 ERROR, 4 WARN, 5–6 INFO, 7 DEBUG
+var prioritySeverities = [...]Severity{
+	SeverityFatal, SeverityFatal, SeverityFatal, SeverityError,
+	SeverityWarn, SeverityInfo, SeverityInfo, SeverityDebug,
+}
+
+// severityPriorities — PRIORITY для записей, разобранных как текст
+var severityPriorities = [...]int{6, 7, 6, 4, 3, 2}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return ""
+	}
+	return severityNames[s]
+}
+
+// Priority — journald PRIORITY, соответствующий уровню
+func (s Severity) Priority() int {
+	if s < 0 || int(s) >= len(severityPriorities) {
+		return 6
+	}
+	return severityPriorities[s]
+}
+
+// ParseSeverity распознаёт имя уровня или синоним без учёта регистра,
+// а также число 0–7 как journald PRIORITY
+func ParseSeverity(name string) (Severity, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if sev, ok := severityAliases[name]; ok {
+		return sev, nil
+	}
+	if prio, err := strconv.Atoi(name); err == nil && prio >= 0 && prio < len(prioritySeverities) {
+		return prioritySeverities[prio], nil
+	}
+	return SeverityUnknown, fmt.Errorf("unknown level %q", name)
+}
+
+// PrioritySeverity — уровень по journald PRIORITY
+func PrioritySeverity(prio int) Severity {
+	if prio < 0 || prio >= len(prioritySeverities) {
+		return SeverityInfo
+	}
+	return prioritySeverities[prio]
+}
+
+// EntrySeverity — уровень записи; если Level не распознан, используется PRIORITY
+func EntrySeverity(entry LogEntry) Severity {
+	if sev, ok := severityAliases[strings.ToUpper(entry.Level)]; ok {
+		return sev
+	}
+	return PrioritySeverity(entry.Priority)
+}
@@ -6,6 +6,17 @@ type LogEntry struct {
 	Timestamp time.Time `json:"timestamp"`
 	Level     string    `json:"level"`
 	Message   string    `json:"message"`
+
+	// Метаданные журнала (пустые, если вывод разобран как текст)
+	Cursor           string `json:"cursor,omitempty"`
+	Unit             string `json:"unit,omitempty"` // _SYSTEMD_UNIT без .service
+	PID              int    `json:"pid,omitempty"`
+	Priority         int    `json:"priority"` // syslog: 0 emerg … 7 debug
+	SyslogIdentifier string `json:"syslog_identifier,omitempty"`
+	BootID           string `json:"boot_id,omitempty"`
+
+	// Поля из сообщения: channel, chat_id, model… (см. fieldExtractor)
+	Fields map[string]string `json:"fields,omitempty"`
 }
 
 type LogRequest struct {
@@ -17,13 +28,31 @@ type LogRequest struct {
 
 type LogResponse struct {
 	Entries []LogEntry `json:"entries"`
-	Total   int        `json:"total"`
-	Unit    string     `json:"unit"`
+	Total   int        `json:"total"` // записей на странице; есть ли ещё — см. HasMore
+	Unit    string     `json:"unit"`  // первый из Units
+	Units   []string   `json:"units"` // юниты запроса; записи слиты по времени
+
+	// Курсоры для соседних страниц: before_cursor — более старые записи, after_cursor — новые
+	BeforeCursor string `json:"before_cursor,omitempty"`
+	AfterCursor  string `json:"after_cursor,omitempty"`
+	HasMore      bool   `json:"has_more"` // в направлении чтения есть ещё записи
+
+	Scanned int  `json:"scanned"`           // сколько записей журнала просмотрено
+	Partial bool `json:"partial,omitempty"` // поиск остановлен по logs.query_budget
 }
 
 type LogFilter struct {
-	Lines  int
-	Level  string
-	Since  string
-	Search string
+	Lines    int
+	Level    string // точный уровень; синонимы приводятся к шкале Severity
+	MinLevel string // уровень не ниже: WARN — это WARN, ERROR и FATAL
+	Since    string
+	Until    string
+	Search   string
+	Regex    string            // регулярное выражение по тексту сообщения
+	Query    string            // выражение на языке запросов (см. ParseQuery)
+	Fields   map[string]string // поле → значение (равенство без учёта регистра)
+
+	// Пагинация по курсорам журнала (взаимоисключающие)
+	AfterCursor  string // записи новее курсора, от старых к новым
+	BeforeCursor string // записи старше курсора
 }
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrumentHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/items/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.WriteHeader(http.StatusInternalServerError) // второй вызов не меняет код
+	})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data"))
+		w.(http.Flusher).Flush()
+	})
+
+	h := NewHistogramVec("test_seconds", "Test.", []float64{60}, "route", "method", "code")
+	handler := InstrumentHandler(mux, h, mux)
+
+	for _, path := range []string{"/api/items/1", "/api/items/2", "/stream", "/nope/42"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if path == "/stream" && !w.Flushed {
+			t.Error("Flush was not passed through")
+		}
+	}
+
+	// Метка route — шаблон mux, а не путь запроса
+	got := scrape(t, h)
+	for _, want := range []string{
+		`test_seconds_count{code="404",method="GET",route="/api/items/"} 2`,
+		`test_seconds_count{code="200",method="GET",route="/stream"} 1`,
+		`test_seconds_count{code="404",method="GET",route="unmatched"} 1`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "/api/items/1") {
+		t.Errorf("request path leaked into labels:\n%s", got)
+	}
+}
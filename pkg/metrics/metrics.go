@@ -0,0 +1,283 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Типы метрик в формате экспозиции Prometheus
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Labels — метки сэмпла
+type Labels map[string]string
+
+// Collector отдаёт свои метрики при каждом scrape
+type Collector interface {
+	Collect(e *Encoder)
+}
+
+// CollectorFunc — функция как Collector
+type CollectorFunc func(e *Encoder)
+
+func (f CollectorFunc) Collect(e *Encoder) { f(e) }
+
+// Encoder пишет метрики в текстовом формате Prometheus 0.0.4
+type Encoder struct {
+	w *bufio.Writer
+}
+
+// Family пишет заголовок семейства метрик (HELP и TYPE)
+func (e *Encoder) Family(name, help, typ string) {
+	fmt.Fprintf(e.w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(e.w, "# TYPE %s %s\n", name, typ)
+}
+
+// Sample пишет одно значение
+func (e *Encoder) Sample(name string, labels Labels, value float64) {
+	e.w.WriteString(name)
+	if len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		e.w.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				e.w.WriteByte(',')
+			}
+			fmt.Fprintf(e.w, "%s=\"%s\"", k, escapeLabel(labels[k]))
+		}
+		e.w.WriteByte('}')
+	}
+	e.w.WriteByte(' ')
+	e.w.WriteString(formatFloat(value))
+	e.w.WriteByte('\n')
+}
+
+// Gauge пишет семейство из одного значения без меток
+func (e *Encoder) Gauge(name, help string, value float64) {
+	e.Family(name, help, TypeGauge)
+	e.Sample(name, nil, value)
+}
+
+// Registry — набор коллекторов, отдаваемых на /metrics
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register добавляет коллекторы
+func (r *Registry) Register(cs ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, cs...)
+}
+
+// ServeHTTP отдаёт все метрики
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e := &Encoder{w: bufio.NewWriter(w)}
+	for _, c := range collectors {
+		c.Collect(e)
+	}
+	e.w.Flush()
+}
+
+// Counter — монотонный счётчик без меток
+type Counter struct {
+	name, help string
+	bits       uint64
+}
+
+func NewCounter(name, help string) *Counter {
+	return &Counter{name: name, help: help}
+}
+
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add увеличивает счётчик на v (v >= 0)
+func (c *Counter) Add(v float64) {
+	for {
+		old := atomic.LoadUint64(&c.bits)
+		next := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&c.bits, old, next) {
+			return
+		}
+	}
+}
+
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+func (c *Counter) Collect(e *Encoder) {
+	e.Family(c.name, c.help, TypeCounter)
+	e.Sample(c.name, nil, c.Value())
+}
+
+// GaugeFunc — gauge, значение которого читается при scrape
+type GaugeFunc struct {
+	name, help string
+	fn         func() float64
+}
+
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return &GaugeFunc{name: name, help: help, fn: fn}
+}
+
+func (g *GaugeFunc) Collect(e *Encoder) {
+	e.Gauge(g.name, g.help, g.fn())
+}
+
+// CounterFunc — counter, значение которого читается при scrape
+type CounterFunc struct {
+	name, help string
+	fn         func() float64
+}
+
+func NewCounterFunc(name, help string, fn func() float64) *CounterFunc {
+	return &CounterFunc{name: name, help: help, fn: fn}
+}
+
+func (c *CounterFunc) Collect(e *Encoder) {
+	e.Family(c.name, c.help, TypeCounter)
+	e.Sample(c.name, nil, c.fn())
+}
+
+// DefaultBuckets — границы гистограмм задержки HTTP в секундах
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HistogramVec — гистограммы с одинаковыми метками
+type HistogramVec struct {
+	name, help string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	labels []string
+	counts []uint64 // по бакетам, не накопительно
+	count  uint64
+	sum    float64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	return &HistogramVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*histogram),
+	}
+}
+
+// Observe добавляет значение; labelValues — в порядке labelNames
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{
+			labels: append([]string(nil), labelValues...),
+			counts: make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+
+	for i, upper := range h.buckets {
+		if value <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) Collect(e *Encoder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	e.Family(h.name, h.help, TypeHistogram)
+
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := h.series[k]
+		labels := Labels{}
+		for i, name := range h.labelNames {
+			if i < len(s.labels) {
+				labels[name] = s.labels[i]
+			}
+		}
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			e.Sample(h.name+"_bucket", withLabel(labels, "le", formatFloat(upper)), float64(cumulative))
+		}
+		e.Sample(h.name+"_bucket", withLabel(labels, "le", "+Inf"), float64(s.count))
+		e.Sample(h.name+"_sum", labels, s.sum)
+		e.Sample(h.name+"_count", labels, float64(s.count))
+	}
+}
+
+func withLabel(labels Labels, name, value string) Labels {
+	result := make(Labels, len(labels)+1)
+	for k, v := range labels {
+		result[k] = v
+	}
+	result[name] = value
+	return result
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// scrape отдаёт вывод Registry с коллекторами cs
+func scrape(t *testing.T, cs ...Collector) string {
+	t.Helper()
+	r := NewRegistry()
+	r.Register(cs...)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	return w.Body.String()
+}
+
+func TestEncoderEscaping(t *testing.T) {
+	got := scrape(t, CollectorFunc(func(e *Encoder) {
+		e.Family("test_info", "Help with a \\ backslash,\na newline and \"quotes\".", TypeGauge)
+		e.Sample("test_info", Labels{"path": `C:\dir "x"` + "\nnext", "a": "first"}, 1)
+		e.Sample("test_info", Labels{}, 2)
+	}))
+
+	want := `# HELP test_info Help with a \\ backslash,\na newline and "quotes".
+# TYPE test_info gauge
+test_info{a="first",path="C:\\dir \"x\"\nnext"} 1
+test_info 2
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "0"},
+		{42, "42"},
+		{0.005, "0.005"},
+		{1e21, "1e+21"},
+		{-3.5, "-3.5"},
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+		{math.NaN(), "NaN"},
+	}
+	for _, tt := range tests {
+		if got := formatFloat(tt.value); got != tt.want {
+			t.Errorf("formatFloat(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	h := NewHistogramVec("test_seconds", "Test latency.", []float64{0.25, 1}, "method", "route")
+	// Граница бакета включается в него, значения больше последней — только в +Inf
+	h.Observe(0.25, "GET", "/a")
+	h.Observe(0.5, "GET", "/a")
+	h.Observe(4, "GET", "/a")
+	h.Observe(0.1, "POST", `/b"`)
+
+	want := `# HELP test_seconds Test latency.
+# TYPE test_seconds histogram
+test_seconds_bucket{le="0.25",method="GET",route="/a"} 1
+test_seconds_bucket{le="1",method="GET",route="/a"} 2
+test_seconds_bucket{le="+Inf",method="GET",route="/a"} 3
+test_seconds_sum{method="GET",route="/a"} 4.75
+test_seconds_count{method="GET",route="/a"} 3
+test_seconds_bucket{le="0.25",method="POST",route="/b\""} 1
+test_seconds_bucket{le="1",method="POST",route="/b\""} 1
+test_seconds_bucket{le="+Inf",method="POST",route="/b\""} 1
+test_seconds_sum{method="POST",route="/b\""} 0.1
+test_seconds_count{method="POST",route="/b\""} 1
+`
+	if got := scrape(t, h); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Семейство без сэмплов — только заголовок
+	empty := NewHistogramVec("empty_seconds", "Nothing yet.", DefaultBuckets, "route")
+	if got := scrape(t, empty); got != "# HELP empty_seconds Nothing yet.\n# TYPE empty_seconds histogram\n" {
+		t.Errorf("empty histogram:\n%s", got)
+	}
+}
+
+func TestCounters(t *testing.T) {
+	c := NewCounter("test_total", "Things.")
+	c.Inc()
+	c.Add(2.5)
+
+	got := scrape(t,
+		c,
+		NewCounterFunc("test_func_total", "From a func.", func() float64 { return 7 }),
+		NewGaugeFunc("test_gauge", "Gauge.", func() float64 { return -1 }),
+	)
+	want := `# HELP test_total Things.
+# TYPE test_total counter
+test_total 3.5
+# HELP test_func_total From a func.
+# TYPE test_func_total counter
+test_func_total 7
+# HELP test_gauge Gauge.
+# TYPE test_gauge gauge
+test_gauge -1
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,200 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// queueSize — сколько сообщений может ждать отправки в одном канале
+const queueSize = 100
+
+// Dispatcher рассылает сообщения по каналам. У каждого канала своя очередь и горутина,
+// так что медленный SMTP не задерживает webhook
+type Dispatcher struct {
+	channels []*channel
+}
+
+type channel struct {
+	name     string
+	notifier Notifier
+	tmpl     *template.Template
+	events   map[string]bool // пусто — все события
+	limiter  *rateLimiter
+	queue    chan Message
+
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// NewDispatcher создаёт каналы из конфигурации и запускает их горутины
+func NewDispatcher(cfg *config.Config) (*Dispatcher, error) {
+	d := &Dispatcher{}
+
+	for _, cc := range cfg.Notify.Channels {
+		var n Notifier
+		switch cc.Type {
+		case "webhook":
+			n = NewWebhook(cc.URL, cc.Headers)
+		case "telegram":
+			n = NewTelegram(cc.APIURL, cc.BotToken, cc.ChatID)
+		case "smtp":
+			n = NewSMTP(cc.Host, cc.Port, cc.Username, cc.Password, cc.From, cc.To)
+		default:
+			return nil, fmt.Errorf("channel %s: unknown type %q", cc.Name, cc.Type)
+		}
+
+		tmpl, err := parseTemplate(cc.Name, cc.Template)
+		if err != nil {
+			return nil, err
+		}
+
+		ch := &channel{
+			name:           cc.Name,
+			notifier:       n,
+			tmpl:           tmpl,
+			events:         map[string]bool{},
+			queue:          make(chan Message, queueSize),
+			maxAttempts:    cfg.Notify.MaxAttempts,
+			initialBackoff: cfg.Notify.InitialBackoff.Std(),
+			maxBackoff:     cfg.Notify.MaxBackoff.Std(),
+		}
+		for _, ev := range cc.Events {
+			ch.events[ev] = true
+		}
+		if cc.RateLimit > 0 {
+			window := cc.RateWindow.Std()
+			if window == 0 {
+				window = time.Minute
+			}
+			ch.limiter = newRateLimiter(cc.RateLimit, window)
+		}
+
+		d.channels = append(d.channels, ch)
+		go ch.run()
+	}
+
+	return d, nil
+}
+
+// Len — количество каналов
+func (d *Dispatcher) Len() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.channels)
+}
+
+// Wants сообщает, подписан ли хоть один канал на события вида kind
+func (d *Dispatcher) Wants(kind string) bool {
+	if d == nil {
+		return false
+	}
+	for _, ch := range d.channels {
+		if len(ch.events) == 0 || ch.events[kind] {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify ставит сообщение в очередь каждого подходящего канала и не блокируется
+func (d *Dispatcher) Notify(msg Message) {
+	if d == nil {
+		return
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+
+	for _, ch := range d.channels {
+		if len(ch.events) > 0 && !ch.events[msg.Kind] {
+			continue
+		}
+		select {
+		case ch.queue <- msg:
+		default:
+			log.Printf("⚠️  Notify %s: queue full, dropping %q", ch.name, msg.Title)
+		}
+	}
+}
+
+func (ch *channel) run() {
+	for msg := range ch.queue {
+		if ch.limiter != nil && !ch.limiter.Allow(time.Now()) {
+			log.Printf("⚠️  Notify %s: rate limit reached, dropping %q", ch.name, msg.Title)
+			continue
+		}
+
+		body, err := render(ch.tmpl, msg)
+		if err != nil {
+			log.Printf("⚠️  Notify %s: template error: %v", ch.name, err)
+			continue
+		}
+
+		if err := ch.send(msg, body); err != nil {
+			log.Printf("❌ Notify %s: giving up on %q: %v", ch.name, msg.Title, err)
+		}
+	}
+}
+
+// send повторяет отправку с экспоненциальной задержкой
+func (ch *channel) send(msg Message, body string) error {
+	backoff := ch.initialBackoff
+	var err error
+	for attempt := 1; attempt <= ch.maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err = ch.notifier.Send(ctx, msg, body)
+		cancel()
+		if err == nil || isPermanent(err) {
+			return err
+		}
+		if attempt == ch.maxAttempts {
+			break
+		}
+
+		log.Printf("⚠️  Notify %s: attempt %d failed: %v (retrying in %s)", ch.name, attempt, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > ch.maxBackoff {
+			backoff = ch.maxBackoff
+		}
+	}
+	return err
+}
+
+// rateLimiter — не больше limit сообщений за скользящее окно window
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	sent   []time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window}
+}
+
+func (l *rateLimiter) Allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(l.sent) && !l.sent[i].After(cutoff) {
+		i++
+	}
+	l.sent = l.sent[i:]
+
+	if len(l.sent) >= l.limit {
+		return false
+	}
+	l.sent = append(l.sent, now)
+	return true
+}
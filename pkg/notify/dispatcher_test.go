@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// notifierFunc — Notifier из функции
+type notifierFunc func(ctx context.Context, msg Message, body string) error
+
+func (f notifierFunc) Send(ctx context.Context, msg Message, body string) error {
+	return f(ctx, msg, body)
+}
+
+func testChannel(n Notifier, attempts int) *channel {
+	tmpl, _ := parseTemplate("test", "")
+	return &channel{
+		name:           "test",
+		notifier:       n,
+		tmpl:           tmpl,
+		queue:          make(chan Message, queueSize),
+		maxAttempts:    attempts,
+		initialBackoff: time.Millisecond,
+		maxBackoff:     2 * time.Millisecond,
+	}
+}
+
+func TestChannelSendRetriesTemporaryErrors(t *testing.T) {
+	var calls int32
+	ch := testChannel(notifierFunc(func(context.Context, Message, string) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("connection reset")
+		}
+		return nil
+	}), 5)
+
+	if err := ch.send(Message{Title: "t"}, "body"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestChannelSendGivesUp(t *testing.T) {
+	var calls int32
+	ch := testChannel(notifierFunc(func(context.Context, Message, string) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("timeout")
+	}), 3)
+
+	if err := ch.send(Message{}, "body"); err == nil {
+		t.Fatal("send succeeded, want error")
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestChannelSendStopsOnPermanentError(t *testing.T) {
+	var calls int32
+	ch := testChannel(notifierFunc(func(context.Context, Message, string) error {
+		atomic.AddInt32(&calls, 1)
+		return &permanentError{errors.New("bad request")}
+	}), 5)
+
+	if err := ch.send(Message{}, "body"); err == nil {
+		t.Fatal("send succeeded, want error")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestChannelRateLimit(t *testing.T) {
+	var calls int32
+	ch := testChannel(notifierFunc(func(context.Context, Message, string) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}), 1)
+	ch.limiter = newRateLimiter(2, time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		ch.run()
+		close(done)
+	}()
+	for i := 0; i < 5; i++ {
+		ch.queue <- Message{Title: "m"}
+	}
+	close(ch.queue)
+	<-done
+
+	if calls != 2 {
+		t.Errorf("sent %d messages, want 2", calls)
+	}
+}
+
+func TestRateLimiterWindow(t *testing.T) {
+	l := newRateLimiter(2, time.Minute)
+	start := time.Now()
+
+	if !l.Allow(start) || !l.Allow(start.Add(time.Second)) {
+		t.Fatal("first two messages must pass")
+	}
+	if l.Allow(start.Add(2 * time.Second)) {
+		t.Error("third message within the window must be dropped")
+	}
+	if !l.Allow(start.Add(time.Minute + time.Millisecond)) {
+		t.Error("message after the window must pass")
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		code      int
+		permanent bool
+	}{
+		{400, true},
+		{401, true},
+		{404, true},
+		{429, false},
+		{500, false},
+		{503, false},
+	}
+	for _, tt := range tests {
+		if got := isPermanent(statusError(tt.code, "")); got != tt.permanent {
+			t.Errorf("statusError(%d) permanent = %v, want %v", tt.code, got, tt.permanent)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Виды событий
+const (
+	KindAlert   = "alert"   // смена состояния алерта
+	KindService = "service" // смена статуса сервиса
+	KindLog     = "log"     // запись ERROR или FATAL в логах
+)
+
+// Message — событие для отправки в каналы
+type Message struct {
+	Kind     string            `json:"kind"`
+	Severity string            `json:"severity"` // info, warning, critical
+	Title    string            `json:"title"`
+	Text     string            `json:"text"`
+	Time     time.Time         `json:"time"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// Notifier отправляет сообщение в один канал. body — сообщение, отрендеренное шаблоном канала
+type Notifier interface {
+	Send(ctx context.Context, msg Message, body string) error
+}
+
+// DefaultTemplate — шаблон по умолчанию; в шаблоне доступны поля Message
+const DefaultTemplate = `[{{.Severity | upper}}] {{.Title}}
+{{.Text}}{{range $k, $v := .Fields}}
+{{$k}}: {{$v}}{{end}}
+{{.Time.Format "2006-01-02 15:04:05 MST"}}`
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("channel %s: invalid template: %w", name, err)
+	}
+	return t, nil
+}
+
+func render(t *template.Template, msg Message) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, msg); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// permanentError — ошибка, после которой повторять отправку бессмысленно (например, 400 или 401)
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// statusError превращает HTTP ответ с ошибкой в error; 4xx кроме 429 не повторяются
+func statusError(code int, body string) error {
+	err := fmt.Errorf("unexpected status %d: %s", code, strings.TrimSpace(body))
+	if code >= 400 && code < 500 && code != 429 {
+		return &permanentError{err}
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP — минимальный SMTP сервер без STARTTLS и AUTH; возвращает конверт и текст письма
+func fakeSMTP(t *testing.T) (host string, port int, result chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	result = make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		var got []string
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO", "HELO":
+				reply("250 fake")
+			case "MAIL", "RCPT":
+				got = append(got, line)
+				reply("250 ok")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				got = append(got, data.String())
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				result <- got
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return "127.0.0.1", addr.Port, result
+}
+
+func TestSMTPSend(t *testing.T) {
+	host, port, result := fakeSMTP(t)
+	s := NewSMTP(host, port, "", "", "dash@example.com", []string{"a@example.com", "b@example.com"})
+
+	msg := Message{Severity: "critical", Title: "Диск заполнен", Time: time.Date(2026, 2, 21, 10, 0, 0, 0, time.UTC)}
+	if err := s.Send(context.Background(), msg, "line 1\nline 2"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	var got []string
+	select {
+	case got = <-result:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server got no QUIT")
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %d commands, want MAIL, 2×RCPT, DATA: %q", len(got), got)
+	}
+	if got[0] != "MAIL FROM:<dash@example.com>" || got[1] != "RCPT TO:<a@example.com>" || got[2] != "RCPT TO:<b@example.com>" {
+		t.Errorf("envelope = %q", got[:3])
+	}
+	data := got[3]
+	for _, want := range []string{
+		"Subject: =?utf-8?q?",
+		"Date: Sat, 21 Feb 2026 10:00:00 +0000\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nline 1\r\nline 2\r\n",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("message lacks %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestSMTPConnectionRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	err = NewSMTP("127.0.0.1", port, "", "", "a@b", []string{"c@d"}).Send(context.Background(), Message{}, "x")
+	if err == nil {
+		t.Fatal("send succeeded without a server")
+	}
+	if isPermanent(err) {
+		t.Errorf("connection error must be retried: %v", err)
+	}
+}
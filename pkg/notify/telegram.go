@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultTelegramAPI — адрес Bot API
+const DefaultTelegramAPI = "https://api.telegram.org"
+
+// telegramMaxLength — ограничение Bot API на длину текста сообщения (в символах)
+const telegramMaxLength = 4096
+
+// Telegram отправляет сообщения через sendMessage Bot API
+type Telegram struct {
+	apiURL string
+	token  string
+	chatID string
+	client *http.Client
+}
+
+func NewTelegram(apiURL, token, chatID string) *Telegram {
+	if apiURL == "" {
+		apiURL = DefaultTelegramAPI
+	}
+	return &Telegram{
+		apiURL: strings.TrimRight(apiURL, "/"),
+		token:  token,
+		chatID: chatID,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *Telegram) Send(ctx context.Context, msg Message, body string) error {
+	body = truncateRunes(body, telegramMaxLength)
+
+	data, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  t.chatID,
+		"text":                     body,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	url := t.apiURL + "/bot" + t.token + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// В тексте ошибки net/http есть URL, а в нём токен бота
+		return errorWithoutSecret(err, t.token)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return statusError(resp.StatusCode, string(text))
+	}
+	return nil
+}
+
+// truncateRunes обрезает текст до max символов по границе руны, заменяя конец на "..."
+func truncateRunes(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max-3]) + "..."
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+func errorWithoutSecret(err error, secret string) error {
+	return &redactedError{msg: strings.ReplaceAll(err.Error(), secret, "***"), err: err}
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const testToken = "123456:SECRET-token"
+
+func TestTelegramSend(t *testing.T) {
+	var path string
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	tg := NewTelegram(srv.URL+"/", testToken, "-100")
+	if err := tg.Send(context.Background(), Message{}, "hello"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if path != "/bot"+testToken+"/sendMessage" {
+		t.Errorf("path = %q", path)
+	}
+	if got["chat_id"] != "-100" || got["text"] != "hello" {
+		t.Errorf("body = %v", got)
+	}
+}
+
+func TestTelegramRetries(t *testing.T) {
+	srv, calls := statusServer(t, 429, 503)
+	if err := testChannel(NewTelegram(srv.URL, testToken, "1"), 5).send(Message{}, "x"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want 3", *calls)
+	}
+
+	srv, calls = statusServer(t, 403)
+	if err := testChannel(NewTelegram(srv.URL, testToken, "1"), 5).send(Message{}, "x"); err == nil {
+		t.Fatal("send succeeded after 403")
+	}
+	if *calls != 1 {
+		t.Errorf("calls after 403 = %d, want 1", *calls)
+	}
+}
+
+func TestTelegramErrorHidesToken(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close() // соединение будет отклонено, ошибка net/http содержит URL
+
+	err := NewTelegram(url, testToken, "1").Send(context.Background(), Message{}, "x")
+	if err == nil {
+		t.Fatal("send to a closed server succeeded")
+	}
+	if strings.Contains(err.Error(), testToken) {
+		t.Errorf("error leaks the bot token: %v", err)
+	}
+}
+
+func TestTelegramTruncatesOnRunes(t *testing.T) {
+	var text string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&body)
+		text = body.Text
+	}))
+	defer srv.Close()
+
+	long := strings.Repeat("ошибка ", 1000) // 7000 символов, 13000 байт
+	if err := NewTelegram(srv.URL, testToken, "1").Send(context.Background(), Message{}, long); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if !utf8.ValidString(text) {
+		t.Error("truncated text is not valid UTF-8")
+	}
+	if n := utf8.RuneCountInString(text); n != telegramMaxLength {
+		t.Errorf("length = %d characters, want %d", n, telegramMaxLength)
+	}
+	if !strings.HasSuffix(text, "...") {
+		t.Error("truncated text must end with ...")
+	}
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Webhook отправляет POST с JSON телом
+type Webhook struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// webhookPayload — тело запроса: поля Message и отрендеренный текст
+type webhookPayload struct {
+	Message
+	Body string `json:"body"`
+}
+
+func NewWebhook(url string, headers map[string]string) *Webhook {
+	return &Webhook{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (w *Webhook) Send(ctx context.Context, msg Message, body string) error {
+	data, err := json.Marshal(webhookPayload{Message: msg, Body: body})
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return statusError(resp.StatusCode, string(text))
+	}
+	return nil
+}
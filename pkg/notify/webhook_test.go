@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// statusServer отвечает кодами из codes по очереди, затем 200
+func statusServer(t *testing.T, codes ...int) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(codes) {
+			w.WriteHeader(codes[n-1])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestWebhookPayload(t *testing.T) {
+	var got webhookPayload
+	var header string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Token")
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	wh := NewWebhook(srv.URL, map[string]string{"X-Token": "secret"})
+	msg := Message{Kind: KindAlert, Severity: "critical", Title: "disk-full"}
+	if err := testChannel(wh, 1).send(msg, "rendered"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if got.Title != "disk-full" || got.Kind != KindAlert || got.Body != "rendered" {
+		t.Errorf("payload = %+v", got)
+	}
+	if header != "secret" {
+		t.Errorf("X-Token = %q, want secret", header)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		codes     []int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"5xx is retried", []int{500, 502}, 3, false},
+		{"429 is retried", []int{429}, 2, false},
+		{"4xx is not retried", []int{400}, 1, true},
+		{"401 is not retried", []int{401}, 1, true},
+		{"gives up after max attempts", []int{500, 500, 500, 500}, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := statusServer(t, tt.codes...)
+			err := testChannel(NewWebhook(srv.URL, nil), 3).send(Message{}, "body")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", *calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	sddbus "github.com/coreos/go-systemd/v22/dbus"
+)
+
+// DBus управляет юнитами через org.freedesktop.systemd1 на системной шине.
+// Права проверяет polkit (org.freedesktop.systemd1.manage-units), sudo не нужен
+type DBus struct {
+	mu   sync.Mutex
+	conn *sddbus.Conn
+}
+
+// NewDBus подключается к системной шине
+func NewDBus(ctx context.Context) (*DBus, error) {
+	conn, err := sddbus.NewSystemConnectionContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &DBus{conn: conn}, nil
+}
+
+func (d *DBus) Name() string { return BackendDBus }
+
+// connection возвращает соединение, переподключаясь после разрыва (например, рестарта dbus)
+func (d *DBus) connection(ctx context.Context) (*sddbus.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.conn != nil && d.conn.Connected() {
+		return d.conn, nil
+	}
+	if d.conn != nil {
+		d.conn.Close()
+	}
+	conn, err := sddbus.NewSystemConnectionContext(ctx)
+	if err != nil {
+		d.conn = nil
+		return nil, fmt.Errorf("systemd D-Bus: %w", err)
+	}
+	d.conn = conn
+	return conn, nil
+}
+
+func (d *DBus) Status(ctx context.Context, unit string) (UnitStatus, error) {
+	conn, err := d.connection(ctx)
+	if err != nil {
+		return UnitStatus{}, err
+	}
+
+	name := fullName(unit)
+	props, err := conn.GetUnitPropertiesContext(ctx, name)
+	if err != nil {
+		return UnitStatus{}, fmt.Errorf("failed to get status of %s: %w", name, err)
+	}
+
+	status := UnitStatus{
+		Unit:          unit,
+		ActiveState:   stringProp(props, "ActiveState"),
+		SubState:      stringProp(props, "SubState"),
+		LoadState:     stringProp(props, "LoadState"),
+		UnitFileState: stringProp(props, "UnitFileState"),
+	}
+	// Время в микросекундах с эпохи, 0 — никогда
+	if usec, ok := props["ActiveEnterTimestamp"].(uint64); ok && usec > 0 {
+		status.ActiveEnterTimestamp = time.UnixMicro(int64(usec))
+	}
+
+	if status.LoadState == "loaded" && strings.HasSuffix(name, ".service") {
+		svc, err := conn.GetUnitTypePropertiesContext(ctx, name, "Service")
+		if err == nil {
+			status.Result = stringProp(svc, "Result")
+			if code, ok := svc["ExecMainStatus"].(int32); ok {
+				status.ExecMainStatus = int(code)
+			}
+		}
+	}
+
+	return status, nil
+}
+
+func (d *DBus) Control(ctx context.Context, unit, action string) error {
+	if !validAction(action) {
+		return fmt.Errorf("invalid action: %s", action)
+	}
+	conn, err := d.connection(ctx)
+	if err != nil {
+		return err
+	}
+
+	name := fullName(unit)
+	done := make(chan string, 1)
+	switch action {
+	case ActionStart:
+		_, err = conn.StartUnitContext(ctx, name, "replace", done)
+	case ActionStop:
+		_, err = conn.StopUnitContext(ctx, name, "replace", done)
+	case ActionRestart:
+		_, err = conn.RestartUnitContext(ctx, name, "replace", done)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s %s: %w", action, name, err)
+	}
+
+	// Ждём результат задания: done, canceled, timeout, failed, dependency, skipped
+	select {
+	case result := <-done:
+		if result != "done" {
+			return fmt.Errorf("failed to %s %s: job %s", action, name, result)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to %s %s: %w", action, name, ctx.Err())
+	}
+}
+
+// Changes подписывается на PropertiesChanged и отдаёт юниты, у которых изменилось
+// ActiveState или SubState. Использует отдельное соединение, закрываемое вместе с ctx
+func (d *DBus) Changes(ctx context.Context) (<-chan string, error) {
+	conn, err := sddbus.NewSystemConnectionContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("systemd D-Bus: %w", err)
+	}
+	if err := conn.Subscribe(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("systemd D-Bus subscribe: %w", err)
+	}
+
+	updates := make(chan *sddbus.PropertiesUpdate, 256)
+	errs := make(chan error, 1)
+	conn.SetPropertiesSubscriber(updates, errs)
+
+	out := make(chan string, 64)
+	go func() {
+		defer close(out)
+		defer conn.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-errs:
+				// Переполнение буфера: часть сигналов потеряна, опрос наверстает
+				continue
+			case u := <-updates:
+				_, active := u.Changed["ActiveState"]
+				_, sub := u.Changed["SubState"]
+				if !active && !sub {
+					continue
+				}
+				select {
+				case out <- u.UnitName:
+				default:
+				}
+			}
+			if !conn.Connected() {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (d *DBus) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn != nil {
+		d.conn.Close()
+		d.conn = nil
+	}
+	return nil
+}
+
+func stringProp(props map[string]interface{}, name string) string {
+	s, _ := props[name].(string)
+	return s
+}
@@ -0,0 +1,76 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exec — запасной бэкенд: `systemctl show` для статуса и `sudo -n systemctl` для действий.
+// Требует записи в sudoers
+type Exec struct{}
+
+func NewExec() *Exec {
+	return &Exec{}
+}
+
+func (e *Exec) Name() string { return BackendExec }
+
+func (e *Exec) Status(ctx context.Context, unit string) (UnitStatus, error) {
+	cmd := exec.CommandContext(ctx, "systemctl", "show",
+		"--property=ActiveState,SubState,LoadState,UnitFileState,ActiveEnterTimestamp,Result,ExecMainStatus",
+		unit)
+	// Формат дат и сообщений systemctl зависит от локали
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	output, err := cmd.Output()
+	if err != nil {
+		return UnitStatus{}, fmt.Errorf("failed to get service status: %w", err)
+	}
+
+	status := UnitStatus{Unit: unit}
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "ActiveState":
+			status.ActiveState = value
+		case "SubState":
+			status.SubState = value
+		case "LoadState":
+			status.LoadState = value
+		case "UnitFileState":
+			status.UnitFileState = value
+		case "ActiveEnterTimestamp":
+			if t, err := time.Parse("Mon 2006-01-02 15:04:05 MST", value); err == nil {
+				status.ActiveEnterTimestamp = t
+			}
+		case "Result":
+			status.Result = value
+		case "ExecMainStatus":
+			status.ExecMainStatus, _ = strconv.Atoi(value)
+		}
+	}
+
+	return status, nil
+}
+
+func (e *Exec) Control(ctx context.Context, unit, action string) error {
+	if !validAction(action) {
+		return fmt.Errorf("invalid action: %s", action)
+	}
+
+	cmd := exec.CommandContext(ctx, "sudo", "-n", "systemctl", action, unit)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to %s service: %w\nOutput: %s", action, err, string(output))
+	}
+	return nil
+}
+
+func (e *Exec) Close() error { return nil }
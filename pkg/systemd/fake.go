@@ -0,0 +1,135 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Fake — юниты в памяти, для разработки без systemd и для тестов обработчиков
+type Fake struct {
+	mu       sync.Mutex
+	units    map[string]UnitStatus
+	err      error
+	watchers []chan string
+}
+
+// NewFake создаёт запущенные юниты с указанными именами
+func NewFake(units ...string) *Fake {
+	f := &Fake{units: make(map[string]UnitStatus)}
+	for _, unit := range units {
+		f.units[unit] = runningStatus(unit, time.Now())
+	}
+	return f
+}
+
+func (f *Fake) Name() string { return BackendFake }
+
+// Set задаёт состояние юнита
+func (f *Fake) Set(status UnitStatus) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.units[status.Unit] = status
+	f.changed(status.Unit)
+}
+
+// FailWith заставляет все следующие вызовы возвращать err (nil — снова работать)
+func (f *Fake) FailWith(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *Fake) Status(ctx context.Context, unit string) (UnitStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return UnitStatus{}, f.err
+	}
+	status, ok := f.units[unit]
+	if !ok {
+		return UnitStatus{Unit: unit, ActiveState: "inactive", SubState: "dead", LoadState: "not-found"}, nil
+	}
+	return status, nil
+}
+
+func (f *Fake) Control(ctx context.Context, unit, action string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	if !validAction(action) {
+		return fmt.Errorf("invalid action: %s", action)
+	}
+	if _, ok := f.units[unit]; !ok {
+		return fmt.Errorf("failed to %s %s: unit not found", action, unit)
+	}
+
+	switch action {
+	case ActionStart, ActionRestart:
+		f.units[unit] = runningStatus(unit, time.Now())
+	case ActionStop:
+		f.units[unit] = UnitStatus{
+			Unit:          unit,
+			ActiveState:   "inactive",
+			SubState:      "dead",
+			LoadState:     "loaded",
+			UnitFileState: "enabled",
+			Result:        "success",
+		}
+	}
+	f.changed(unit)
+	return nil
+}
+
+// Changes отдаёт юниты, изменённые через Control или Set
+func (f *Fake) Changes(ctx context.Context) (<-chan string, error) {
+	ch := make(chan string, 16)
+
+	f.mu.Lock()
+	f.watchers = append(f.watchers, ch)
+	f.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, w := range f.watchers {
+			if w == ch {
+				f.watchers = append(f.watchers[:i], f.watchers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// changed уведомляет подписчиков Changes; вызывается под f.mu
+func (f *Fake) changed(unit string) {
+	for _, ch := range f.watchers {
+		select {
+		case ch <- unit:
+		default:
+		}
+	}
+}
+
+func (f *Fake) Close() error { return nil }
+
+func runningStatus(unit string, since time.Time) UnitStatus {
+	return UnitStatus{
+		Unit:                 unit,
+		ActiveState:          "active",
+		SubState:             "running",
+		LoadState:            "loaded",
+		UnitFileState:        "enabled",
+		ActiveEnterTimestamp: since,
+		Result:               "success",
+	}
+}
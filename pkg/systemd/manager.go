@@ -0,0 +1,88 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+// Бэкенды
+const (
+	BackendAuto = "auto" // D-Bus, если доступна системная шина, иначе systemctl
+	BackendDBus = "dbus"
+	BackendExec = "exec"
+	BackendFake = "fake"
+)
+
+// Действия над юнитом
+const (
+	ActionStart   = "start"
+	ActionStop    = "stop"
+	ActionRestart = "restart"
+)
+
+// UnitStatus — состояние юнита
+type UnitStatus struct {
+	Unit                 string
+	ActiveState          string // active, reloading, inactive, failed, activating, deactivating
+	SubState             string // running, dead, exited, auto-restart, ...
+	LoadState            string // loaded, not-found, masked, ...
+	UnitFileState        string // enabled, disabled, static, ...
+	ActiveEnterTimestamp time.Time
+	Result               string // success, exit-code, signal, timeout, ... (только для .service)
+	ExecMainStatus       int    // код выхода (или номер сигнала) главного процесса
+}
+
+// Manager — доступ к systemd
+type Manager interface {
+	// Name — имя бэкенда для логов
+	Name() string
+	Status(ctx context.Context, unit string) (UnitStatus, error)
+	// Control выполняет start, stop или restart и ждёт завершения задания
+	Control(ctx context.Context, unit, action string) error
+	Close() error
+}
+
+// New создаёт бэкенд из service.backend
+func New(cfg *config.Config) (Manager, error) {
+	switch cfg.Service.Backend {
+	case BackendDBus:
+		return NewDBus(context.Background())
+	case BackendExec:
+		return NewExec(), nil
+	case BackendFake:
+		return NewFake(cfg.Service.AllowedUnits()...), nil
+	case BackendAuto, "":
+		m, err := NewDBus(context.Background())
+		if err != nil {
+			log.Printf("⚠️  systemd D-Bus unavailable (%v), falling back to systemctl", err)
+			return NewExec(), nil
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unknown service backend %q", cfg.Service.Backend)
+}
+
+func validAction(action string) bool {
+	return action == ActionStart || action == ActionStop || action == ActionRestart
+}
+
+// unitSuffixes — типы юнитов; имя без суффикса считается .service, как в systemctl
+var unitSuffixes = []string{
+	".service", ".socket", ".target", ".timer", ".path", ".mount",
+	".automount", ".swap", ".slice", ".scope", ".device",
+}
+
+// fullName дополняет имя юнита суффиксом .service
+func fullName(unit string) string {
+	for _, suffix := range unitSuffixes {
+		if strings.HasSuffix(unit, suffix) {
+			return unit
+		}
+	}
+	return unit + ".service"
+}
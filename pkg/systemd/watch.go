@@ -0,0 +1,136 @@
+package systemd
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// StateChange — переход юнита в другое состояние
+type StateChange struct {
+	Unit     string
+	Previous UnitStatus
+	Current  UnitStatus
+	Time     time.Time
+}
+
+// ChangeSource — бэкенд, который сам сообщает об изменениях юнитов (D-Bus сигналы, фейк).
+// Канал отдаёт имена юнитов (как в конфиге или полные) и закрывается при ошибке или отмене ctx
+type ChangeSource interface {
+	Changes(ctx context.Context) (<-chan string, error)
+}
+
+// Watcher следит за состоянием юнитов и сообщает о переходах.
+// Если бэкенд умеет ChangeSource, статус перечитывается сразу по сигналу;
+// опрос с интервалом остаётся как страховка от пропущенных сигналов
+type Watcher struct {
+	manager  Manager
+	units    []string
+	interval time.Duration
+
+	mu          sync.Mutex
+	last        map[string]UnitStatus
+	subscribers []func(StateChange)
+}
+
+func NewWatcher(m Manager, units []string, interval time.Duration) *Watcher {
+	return &Watcher{
+		manager:  m,
+		units:    units,
+		interval: interval,
+		last:     make(map[string]UnitStatus),
+	}
+}
+
+// Subscribe регистрирует обработчик переходов. Вызывается из горутины Run
+func (w *Watcher) Subscribe(fn func(StateChange)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Run работает до отмены ctx
+func (w *Watcher) Run(ctx context.Context) {
+	// Запоминаем начальные состояния без событий
+	for _, unit := range w.units {
+		w.check(ctx, unit, false)
+	}
+
+	var changes <-chan string
+	source, hasSource := w.manager.(ChangeSource)
+	subscribe := func() {
+		if !hasSource {
+			return
+		}
+		ch, err := source.Changes(ctx)
+		if err != nil {
+			log.Printf("⚠️  Unit watcher: %v (polling every %s)", err, w.interval)
+			return
+		}
+		changes = ch
+	}
+	subscribe()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case name, ok := <-changes:
+			if !ok {
+				// Подписка оборвалась — переподписываемся на следующем тике
+				changes = nil
+				continue
+			}
+			if unit, ok := w.match(name); ok {
+				w.check(ctx, unit, true)
+			}
+		case <-ticker.C:
+			if hasSource && changes == nil {
+				subscribe()
+			}
+			for _, unit := range w.units {
+				w.check(ctx, unit, true)
+			}
+		}
+	}
+}
+
+// match находит юнит из списка по имени из сигнала
+func (w *Watcher) match(name string) (string, bool) {
+	for _, unit := range w.units {
+		if unit == name || fullName(unit) == name {
+			return unit, true
+		}
+	}
+	return "", false
+}
+
+// check перечитывает статус и рассылает событие, если ActiveState или SubState изменились
+func (w *Watcher) check(ctx context.Context, unit string, notify bool) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	status, err := w.manager.Status(ctx, unit)
+	cancel()
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	prev, seen := w.last[unit]
+	w.last[unit] = status
+	subscribers := make([]func(StateChange), len(w.subscribers))
+	copy(subscribers, w.subscribers)
+	w.mu.Unlock()
+
+	if !notify || !seen || (prev.ActiveState == status.ActiveState && prev.SubState == status.SubState) {
+		return
+	}
+
+	change := StateChange{Unit: unit, Previous: prev, Current: status, Time: time.Now()}
+	for _, fn := range subscribers {
+		fn(change)
+	}
+}
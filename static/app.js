@@ -1,5 +1,17 @@
 // PicoClaw Dashboard Client
 
+// When authentication is enabled, an expired session makes the API return 401:
+// send the user to the login page instead of failing silently.
+const originalFetch = window.fetch.bind(window);
+window.fetch = async (...args) => {
+    const response = await originalFetch(...args);
+    if (response.status === 401) {
+        const next = encodeURIComponent(window.location.pathname + window.location.search);
+        window.location.href = `/static/login.html?next=${next}`;
+    }
+    return response;
+};
+
 class Dashboard {
     constructor() {
         this.ws = null;
@@ -11,6 +23,7 @@ class Dashboard {
     init() {
         this.connectWebSocket();
         this.initTabs();
+        this.initAuth();
         // Also fetch initial data via REST as fallback
         this.fetchHealth();
         this.fetchServiceStatus();
@@ -45,6 +58,32 @@ class Dashboard {
         });
     }
 
+    async initAuth() {
+        try {
+            const response = await fetch('/api/auth/me');
+            if (!response.ok) return;
+            const data = await response.json();
+
+            // Hide controls the current role is not allowed to use
+            const permissions = data.permissions || [];
+            if (!permissions.includes('service:control')) {
+                document.querySelector('.service-buttons')?.setAttribute('hidden', '');
+            }
+
+            if (!data.auth_enabled) return;
+
+            const button = document.getElementById('btn-logout');
+            button.textContent = `🚪 Sign out (${data.identity.username})`;
+            button.hidden = false;
+            button.addEventListener('click', async () => {
+                await fetch('/api/auth/logout', { method: 'POST' });
+                window.location.href = '/static/login.html';
+            });
+        } catch (e) {
+            console.error('Error fetching auth status:', e);
+        }
+    }
+
     connectWebSocket() {
         const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
         const wsUrl = `${protocol}//${window.location.host}/ws`;
@@ -58,6 +97,10 @@ class Dashboard {
             this.setStatus('connected', 'Connected via WebSocket');
             console.log('✅ WebSocket connected');
 
+            // Subscribe to the topics this page renders
+            this.ws.send(JSON.stringify({ type: 'subscribe', topics: ['metrics', 'service', 'alerts'] }));
+            window.logsViewer.resubscribe();
+
             // Request initial data
             this.fetchHealth();
             this.fetchServiceStatus();
@@ -65,8 +108,32 @@ class Dashboard {
 
         this.ws.onmessage = (event) => {
             try {
-                const data = JSON.parse(event.data);
-                this.updateDashboard(data);
+                const msg = JSON.parse(event.data);
+                if (msg.type === 'error') {
+                    console.warn('WebSocket:', msg.payload.error);
+                    return;
+                }
+                if (msg.type !== 'event') {
+                    return;
+                }
+
+                const data = msg.payload;
+                switch (msg.topic) {
+                    case 'metrics':
+                        this.updateDashboard(data);
+                        break;
+                    case 'alerts':
+                        console.warn(`🚨 Alert ${data.alert.rule.name}: ${data.previous} → ${data.alert.state}`);
+                        break;
+                    case 'service':
+                        if (window.serviceControl.currentStatus?.unit === data.unit) {
+                            window.serviceControl.updateStatus(data.status);
+                        }
+                        break;
+                    case 'logs':
+                        window.logsViewer.appendLogEntry(data);
+                        break;
+                }
             } catch (e) {
                 console.error('Error parsing WebSocket message:', e);
             }
@@ -695,14 +762,22 @@ setInterval(() => {
 class LogsViewer {
     constructor() {
         this.eventSource = null;
+        this.wsStreaming = false;
         this.isStreaming = false;
+        this.beforeCursor = null;
+        this.hasMore = false;
+        this.loadingOlder = false;
         this.logsContent = document.getElementById('logsContent');
         this.refreshBtn = document.getElementById('refreshBtn');
         this.streamBtn = document.getElementById('streamBtn');
         this.clearBtn = document.getElementById('clearBtn');
+        this.exportBtn = document.getElementById('exportBtn');
+        this.unitFilter = document.getElementById('unitFilter');
+        this.allowedUnits = [];
         this.levelFilter = document.getElementById('levelFilter');
         this.timeFilter = document.getElementById('timeFilter');
         this.searchInput = document.getElementById('searchInput');
+        this.queryMode = document.getElementById('queryMode');
         this.linesInput = document.getElementById('linesInput');
         this.streamIndicator = document.getElementById('streamIndicator');
         this.logStats = document.getElementById('logStats');
@@ -714,6 +789,12 @@ class LogsViewer {
         this.refreshBtn.addEventListener('click', () => this.loadLogs());
         this.streamBtn.addEventListener('click', () => this.toggleStream());
         this.clearBtn.addEventListener('click', () => this.clearLogs());
+        this.exportBtn.addEventListener('click', () => this.exportLogs());
+
+        this.unitFilter.addEventListener('change', () => {
+            if (this.isStreaming) this.stopStream();
+            this.loadLogs();
+        });
 
         this.levelFilter.addEventListener('change', () => {
             if (this.isStreaming) this.stopStream();
@@ -728,38 +809,167 @@ class LogsViewer {
                 this.loadLogs();
             }
         });
+
+        this.queryMode.addEventListener('change', () => {
+            this.searchInput.placeholder = this.queryMode.checked ? 'Query, e.g. level>=WARN msg:timeout' : 'Search...';
+            if (!this.searchInput.value) return;
+            if (this.isStreaming) this.stopStream();
+            this.loadLogs();
+        });
+
+        // Clicking a field adds it to the query: field.channel="telegram".
+        // Plain search text becomes a quoted term of the query
+        this.logsContent.addEventListener('click', (e) => {
+            const tag = e.target.closest('.log-field');
+            if (!tag) return;
+            if (!this.queryMode.checked) {
+                this.queryMode.checked = true;
+                this.searchInput.placeholder = 'Query, e.g. level>=WARN msg:timeout';
+                if (this.searchInput.value) this.searchInput.value = this.quoteQuery(this.searchInput.value);
+            }
+            const term = `field.${tag.dataset.name}=${this.quoteQuery(tag.dataset.value)}`;
+            this.searchInput.value = this.searchInput.value ? `${this.searchInput.value} ${term}` : term;
+            if (this.isStreaming) this.stopStream();
+            this.loadLogs();
+        });
+
+        // Infinite scroll: load older entries when scrolled to the top
+        this.logsContent.addEventListener('scroll', () => {
+            if (this.logsContent.scrollTop < 20) this.loadOlder();
+        });
+
+        this.loadUnits();
     }
 
-    async loadLogs() {
-        const filters = {
-            lines: parseInt(this.linesInput.value) || 100,
-            level: this.levelFilter.value,
-            since: this.timeFilter.value,
-            search: this.searchInput.value
-        };
+    // Shows the unit selector when logs.units allows more than one unit
+    async loadUnits() {
+        try {
+            const response = await fetch('/api/logs/units');
+            if (!response.ok) return;
+            const data = await response.json();
+            this.allowedUnits = data.allowed || [];
+        } catch (error) {
+            return;
+        }
+        if (this.allowedUnits.length < 2) return;
+
+        const options = this.allowedUnits.map(unit =>
+            `<option value="${this.escapeHtml(unit)}">${this.escapeHtml(unit)}</option>`);
+        options.push('<option value="*">All units</option>');
+        this.unitFilter.innerHTML = options.join('');
+        this.unitFilter.hidden = false;
+    }
+
+    // Units to read; empty means the server default (logs.unit)
+    selectedUnits() {
+        if (this.unitFilter.hidden || !this.unitFilter.value) return [];
+        if (this.unitFilter.value === '*') return this.allowedUnits;
+        return [this.unitFilter.value];
+    }
+
+    unitHtml(entry) {
+        if (this.selectedUnits().length < 2 || !entry.unit) return '';
+        return `<span class="log-unit">${this.escapeHtml(entry.unit)}</span>`;
+    }
+
+    // Fields extracted from the message (channel, chat_id, ...) as clickable tags
+    fieldsHtml(entry) {
+        if (!entry.fields) return '';
+        return Object.keys(entry.fields).sort()
+            .filter(name => /^[\w.-]+$/.test(name))
+            .map(name => `<span class="log-field" data-name="${this.escapeAttr(name)}" data-value="${this.escapeAttr(entry.fields[name])}" title="Filter by ${this.escapeAttr(name)}">${this.escapeHtml(name)}=${this.escapeHtml(entry.fields[name])}</span>`)
+            .join('');
+    }
+
+    // Quotes text as a query string term
+    quoteQuery(text) {
+        return `"${text.replace(/\\/g, '\\\\').replace(/"/g, '\\"')}"`;
+    }
+
+    // The search box goes to q in query mode, otherwise to the plain substring search
+    searchParamName() {
+        return this.queryMode.checked ? 'q' : 'search';
+    }
 
+    filterParams() {
+        const params = new URLSearchParams();
+        this.selectedUnits().forEach(unit => params.append('unit', unit));
+        if (this.levelFilter.value) params.set('min_level', this.levelFilter.value);
+        if (this.timeFilter.value) params.set('since', this.timeFilter.value);
+        if (this.searchInput.value) params.set(this.searchParamName(), this.searchInput.value);
+        params.set('lines', (parseInt(this.linesInput.value) || 100).toString());
+        return params;
+    }
+
+    // Downloads the selected period with the current filters (the server defaults to the last hour)
+    exportLogs() {
+        const params = this.filterParams();
+        params.delete('lines');
+        const since = params.get('since');
+        params.delete('since');
+        if (since) params.set('from', since);
+        params.set('format', 'text');
+        window.location.href = `/api/logs/export?${params}`;
+    }
+
+    async loadOlder() {
+        if (!this.hasMore || !this.beforeCursor || this.loadingOlder || this.isStreaming) return;
+        this.loadingOlder = true;
+
+        try {
+            const params = this.filterParams();
+            params.set('before_cursor', this.beforeCursor);
+
+            const response = await fetch(`/api/logs?${params}`);
+            if (!response.ok) {
+                throw new Error('Failed to load older logs');
+            }
+
+            const data = await response.json();
+            this.beforeCursor = data.before_cursor;
+            this.hasMore = data.has_more;
+
+            const entries = data.entries || [];
+            if (entries.length > 0) {
+                const previousHeight = this.logsContent.scrollHeight;
+                this.logsContent.insertAdjacentHTML('afterbegin', entries.map(entry => this.entryHtml(entry)).join(''));
+                // Keep the entry that was on top in place
+                this.logsContent.scrollTop += this.logsContent.scrollHeight - previousHeight;
+
+                const stats = this.logStats.textContent.match(/(\d+) entries/);
+                const count = (stats ? parseInt(stats[1]) : 0) + entries.length;
+                this.logStats.textContent = `${count} entries`;
+            }
+        } catch (error) {
+            console.error(error);
+        } finally {
+            this.loadingOlder = false;
+        }
+    }
+
+    async loadLogs() {
         this.refreshBtn.disabled = true;
         this.refreshBtn.textContent = '⏳ Loading...';
 
         try {
-            const params = new URLSearchParams();
-            if (filters.level) params.set('level', filters.level);
-            if (filters.since) params.set('since', filters.since);
-            if (filters.search) params.set('search', filters.search);
-            params.set('lines', filters.lines.toString());
+            const params = this.filterParams();
 
             const response = await fetch(`/api/logs?${params}`);
             if (!response.ok) {
-                throw new Error('Failed to load logs');
+                // 400 explains what is wrong with the query
+                const message = response.status === 400 ? (await response.text()).trim() : '';
+                throw new Error(message || 'Failed to load logs');
             }
 
             const data = await response.json();
+            this.beforeCursor = data.before_cursor;
+            this.hasMore = data.has_more;
             this.renderLogs(data.entries);
             this.logStats.textContent = `${data.total} entries`;
             this.headerStats.textContent = `Total: ${data.total}`;
         } catch (error) {
             this.logsContent.innerHTML = `<div style="color: var(--danger); padding: 20px; text-align: center;">
-                ❌ Error: ${error.message}
+                ❌ Error: ${this.escapeHtml(error.message)}
             </div>`;
         } finally {
             this.refreshBtn.disabled = false;
@@ -773,16 +983,21 @@ class LogsViewer {
             return;
         }
 
-        const html = entries.map(entry => `
+        const html = entries.map(entry => this.entryHtml(entry)).join('');
+
+        this.logsContent.innerHTML = html;
+        this.logsContent.scrollTop = this.logsContent.scrollHeight;
+    }
+
+    entryHtml(entry) {
+        return `
             <div class="log-entry ${this.getLogLevelClass(entry.level)}">
                 <span class="log-timestamp">${this.formatTimestamp(entry.timestamp)}</span>
                 <span class="log-level log-level-${entry.level.toLowerCase()}">${entry.level}</span>
-                <span class="log-message">${this.escapeHtml(entry.message)}</span>
+                ${this.unitHtml(entry)}
+                <span class="log-message">${this.escapeHtml(entry.message)}${this.fieldsHtml(entry)}</span>
             </div>
-        `).join('');
-
-        this.logsContent.innerHTML = html;
-        this.logsContent.scrollTop = this.logsContent.scrollHeight;
+        `;
     }
 
     appendLogEntry(entry) {
@@ -791,7 +1006,8 @@ class LogsViewer {
         logEntry.innerHTML = `
             <span class="log-timestamp">${this.formatTimestamp(entry.timestamp)}</span>
             <span class="log-level log-level-${entry.level.toLowerCase()}">${entry.level}</span>
-            <span class="log-message">${this.escapeHtml(entry.message)}</span>
+            ${this.unitHtml(entry)}
+            <span class="log-message">${this.escapeHtml(entry.message)}${this.fieldsHtml(entry)}</span>
         `;
 
         this.logsContent.appendChild(logEntry);
@@ -812,9 +1028,38 @@ class LogsViewer {
     }
 
     startStream() {
+        // Prefer the dashboard WebSocket; fall back to SSE when it is not connected
+        const ws = window.dashboard.ws;
+        if (ws && ws.readyState === WebSocket.OPEN) {
+            this.wsStreaming = true;
+            this.resubscribe();
+        } else {
+            this.startEventSource();
+        }
+
+        this.isStreaming = true;
+        this.streamBtn.textContent = '⏹️ Stop';
+        this.streamBtn.classList.add('streaming');
+        this.streamIndicator.classList.add('active');
+        this.refreshBtn.disabled = true;
+    }
+
+    // Re-sends the logs subscription, e.g. after the WebSocket reconnects
+    resubscribe() {
+        if (!this.wsStreaming) return;
+        const params = {};
+        const units = this.selectedUnits();
+        if (units.length) params.units = units;
+        if (this.levelFilter.value) params.min_level = this.levelFilter.value;
+        if (this.searchInput.value) params[this.queryMode.checked ? 'query' : 'search'] = this.searchInput.value;
+        window.dashboard.ws.send(JSON.stringify({ type: 'subscribe', topics: ['logs'], params }));
+    }
+
+    startEventSource() {
         const params = new URLSearchParams();
-        if (this.levelFilter.value) params.set('level', this.levelFilter.value);
-        if (this.searchInput.value) params.set('search', this.searchInput.value);
+        this.selectedUnits().forEach(unit => params.append('unit', unit));
+        if (this.levelFilter.value) params.set('min_level', this.levelFilter.value);
+        if (this.searchInput.value) params.set(this.searchParamName(), this.searchInput.value);
 
         this.eventSource = new EventSource(`/api/logs/stream?${params}`);
 
@@ -827,15 +1072,13 @@ class LogsViewer {
             }
         };
 
+        // EventSource reconnects by itself and sends Last-Event-ID,
+        // so the server replays what was missed; give up only when it stops retrying
         this.eventSource.onerror = () => {
-            this.stopStream();
+            if (this.eventSource.readyState === EventSource.CLOSED) {
+                this.stopStream();
+            }
         };
-
-        this.isStreaming = true;
-        this.streamBtn.textContent = '⏹️ Stop';
-        this.streamBtn.classList.add('streaming');
-        this.streamIndicator.classList.add('active');
-        this.refreshBtn.disabled = true;
     }
 
     stopStream() {
@@ -843,6 +1086,13 @@ class LogsViewer {
             this.eventSource.close();
             this.eventSource = null;
         }
+        if (this.wsStreaming) {
+            this.wsStreaming = false;
+            const ws = window.dashboard.ws;
+            if (ws && ws.readyState === WebSocket.OPEN) {
+                ws.send(JSON.stringify({ type: 'unsubscribe', topics: ['logs'] }));
+            }
+        }
         this.isStreaming = false;
         this.streamBtn.textContent = '▶️ Live';
         this.streamBtn.classList.remove('streaming');
@@ -852,6 +1102,7 @@ class LogsViewer {
 
     clearLogs() {
         if (this.isStreaming) this.stopStream();
+        this.hasMore = false;
         this.logsContent.innerHTML = '<div style="color: var(--text-secondary); text-align: center; padding: 20px;">No entries</div>';
         this.logStats.textContent = '';
     }
@@ -875,4 +1126,8 @@ class LogsViewer {
         div.textContent = text;
         return div.innerHTML;
     }
+
+    escapeAttr(text) {
+        return this.escapeHtml(text).replace(/"/g, '&quot;');
+    }
 }
@@ -2,10 +2,15 @@ package websocket
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
 
 	"github.com/gorilla/websocket"
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
 )
 
 var upgrader = websocket.Upgrader{
@@ -17,78 +22,328 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// maxCommandSize — предел размера сообщения клиента; команды подписки короткие
+const maxCommandSize = 64 * 1024
+
+// Топики, на которые может подписаться клиент
+const (
+	TopicMetrics = "metrics" // HealthResponse на каждом тике
+	TopicService = "service" // переходы юнитов
+	TopicAlerts  = "alerts"  // смены состояний алертов
+	TopicLogs    = "logs"    // записи журнала, с фильтрами в params
+)
+
+var knownTopics = map[string]bool{
+	TopicMetrics: true,
+	TopicService: true,
+	TopicAlerts:  true,
+	TopicLogs:    true,
+}
+
+// Типы сообщений сервер → клиент
+const (
+	TypeEvent        = "event"        // данные топика
+	TypeSubscribed   = "subscribed"   // ответ на subscribe, payload — текущие подписки
+	TypeUnsubscribed = "unsubscribed" // ответ на unsubscribe, payload — текущие подписки
+	TypeError        = "error"
+)
+
+// Типы команд клиент → сервер
+const (
+	CommandSubscribe   = "subscribe"
+	CommandUnsubscribe = "unsubscribe"
+)
+
+// Envelope — любое сообщение сервера. Seq растёт на 1 для каждого сообщения клиенту,
+// пропуск означает потерянные сообщения
+type Envelope struct {
+	Type    string          `json:"type"`
+	Topic   string          `json:"topic,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Seq     uint64          `json:"seq"`
+}
+
+// Command — сообщение клиента: {"type": "subscribe", "topics": ["metrics", "alerts"]}.
+// Params передаются обработчику топика (см. Hub.Handle), остальные топики их игнорируют
+type Command struct {
+	Type   string          `json:"type"`
+	Topics []string        `json:"topics"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// SubscriptionsPayload — payload ответов subscribed/unsubscribed
+type SubscriptionsPayload struct {
+	Topics []string `json:"topics"`
+}
+
+// ErrorPayload — payload сообщения error
+type ErrorPayload struct {
+	Error string `json:"error"`
+}
+
+// Authorizer решает, можно ли клиенту подписаться на топик (проверка роли)
+type Authorizer func(topic string) bool
+
+// Subscription — подписка клиента на топик с параметрами.
+// Все методы вызываются из горутины Hub.Run и не должны блокироваться
+type Subscription interface {
+	// Match решает, получит ли клиент сообщение (payload — значение, переданное в Publish)
+	Match(payload interface{}) bool
+	// Close вызывается при отписке, повторной подписке с новыми параметрами и отключении клиента
+	Close()
+}
+
+// TopicHandler создаёт подписку по params из команды subscribe.
+// Ошибка возвращается клиенту, подписка не создаётся
+type TopicHandler func(params json.RawMessage) (Subscription, error)
+
 type Hub struct {
 	clients    map[*Client]bool
 	register   chan *Client
 	unregister chan *Client
-	broadcast  chan interface{}
+	publish    chan publication
+	commands   chan clientCommand
+	sendBuffer int
+
+	handlersMu sync.RWMutex
+	handlers   map[string]TopicHandler
+
+	// Счётчики для /metrics (читаются из других горутин)
+	clientCount       int64
+	droppedBroadcasts uint64
+	droppedClients    uint64
+}
+
+type publication struct {
+	topic   string
+	value   interface{} // для Subscription.Match
+	payload json.RawMessage
+}
+
+type clientCommand struct {
+	client  *Client
+	command Command
+	err     error // команда не разобрана
 }
 
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan interface{}
+	hub       *Hub
+	conn      *websocket.Conn
+	send      chan []byte
+	authorize Authorizer
+
+	// Доступны только из горутины Hub.Run. Для топиков без обработчика подписка nil
+	topics map[string]Subscription
+	seq    uint64
 }
 
-func NewHub() *Hub {
+func NewHub(cfg *config.Config) *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
-		broadcast:  make(chan interface{}, 256),
+		publish:    make(chan publication, cfg.WebSocket.BroadcastBuffer),
+		commands:   make(chan clientCommand),
+		sendBuffer: cfg.WebSocket.SendBuffer,
+		handlers:   make(map[string]TopicHandler),
 	}
 }
 
+// Handle задаёт обработчик подписок на топик
+func (h *Hub) Handle(topic string, handler TopicHandler) {
+	h.handlersMu.Lock()
+	defer h.handlersMu.Unlock()
+	h.handlers[topic] = handler
+}
+
+func (h *Hub) handler(topic string) TopicHandler {
+	h.handlersMu.RLock()
+	defer h.handlersMu.RUnlock()
+	return h.handlers[topic]
+}
+
 func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.register:
 			h.clients[client] = true
+			atomic.StoreInt64(&h.clientCount, int64(len(h.clients)))
 
 		case client := <-h.unregister:
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
+			h.remove(client)
 
-		case message := <-h.broadcast:
-			data, err := json.Marshal(message)
-			if err != nil {
-				log.Printf("⚠️  Error broadcasting message: %v", err)
+		case cmd := <-h.commands:
+			if !h.clients[cmd.client] {
+				continue
+			}
+			if cmd.err != nil {
+				h.deliverJSON(cmd.client, TypeError, "", ErrorPayload{Error: "invalid command: " + cmd.err.Error()})
 				continue
 			}
+			h.handleCommand(cmd.client, cmd.command)
 
+		case p := <-h.publish:
 			for client := range h.clients {
-				select {
-				case client.send <- data:
-				default:
-					close(client.send)
-					delete(h.clients, client)
+				sub, ok := client.topics[p.topic]
+				if ok && (sub == nil || sub.Match(p.value)) {
+					h.deliver(client, Envelope{Type: TypeEvent, Topic: p.topic, Payload: p.payload})
 				}
 			}
 		}
 	}
 }
 
-func (h *Hub) Broadcast(message interface{}) {
+// handleCommand меняет подписки клиента и отвечает текущим списком или ошибкой
+func (h *Hub) handleCommand(c *Client, cmd Command) {
+	var replyType string
+	switch cmd.Type {
+	case CommandSubscribe:
+		replyType = TypeSubscribed
+	case CommandUnsubscribe:
+		replyType = TypeUnsubscribed
+	default:
+		h.deliverJSON(c, TypeError, "", ErrorPayload{Error: fmt.Sprintf("unknown command %q", cmd.Type)})
+		return
+	}
+
+	// Ответ с ошибкой может переполнить буфер и отключить клиента — тогда команда
+	// дальше не выполняется
+	for _, topic := range cmd.Topics {
+		if !h.clients[c] {
+			return
+		}
+		if !knownTopics[topic] {
+			h.deliverJSON(c, TypeError, topic, ErrorPayload{Error: fmt.Sprintf("unknown topic %q", topic)})
+			continue
+		}
+		if cmd.Type == CommandUnsubscribe {
+			c.unsubscribe(topic)
+			continue
+		}
+		if c.authorize != nil && !c.authorize(topic) {
+			h.deliverJSON(c, TypeError, topic, ErrorPayload{Error: fmt.Sprintf("forbidden: no access to topic %q", topic)})
+			continue
+		}
+
+		var sub Subscription
+		if handler := h.handler(topic); handler != nil {
+			var err error
+			if sub, err = handler(cmd.Params); err != nil {
+				h.deliverJSON(c, TypeError, topic, ErrorPayload{Error: err.Error()})
+				continue
+			}
+		}
+		if !h.clients[c] {
+			if sub != nil {
+				sub.Close()
+			}
+			return
+		}
+		c.unsubscribe(topic)
+		c.topics[topic] = sub
+	}
+	if !h.clients[c] {
+		return
+	}
+
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	h.deliverJSON(c, replyType, "", SubscriptionsPayload{Topics: topics})
+}
+
+func (h *Hub) deliverJSON(c *Client, typ, topic string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("⚠️  Error marshaling message: %v", err)
+		return
+	}
+	h.deliver(c, Envelope{Type: typ, Topic: topic, Payload: data})
+}
+
+// deliver нумерует сообщение и кладёт его в буфер клиента; переполненный клиент отключается.
+// Отключённому клиенту (c.send закрыт) ничего не отправляется
+func (h *Hub) deliver(c *Client, env Envelope) {
+	if !h.clients[c] {
+		return
+	}
+	c.seq++
+	env.Seq = c.seq
+	data, err := json.Marshal(env)
+	if err != nil {
+		log.Printf("⚠️  Error marshaling message: %v", err)
+		return
+	}
+
 	select {
-	case h.broadcast <- message:
+	case c.send <- data:
 	default:
+		h.remove(c)
+		atomic.AddUint64(&h.droppedClients, 1)
+	}
+}
+
+func (h *Hub) remove(c *Client) {
+	if _, ok := h.clients[c]; ok {
+		for topic := range c.topics {
+			c.unsubscribe(topic)
+		}
+		delete(h.clients, c)
+		close(c.send)
+		atomic.StoreInt64(&h.clientCount, int64(len(h.clients)))
+	}
+}
+
+// Publish рассылает payload подписчикам топика. Не блокируется: при переполненной
+// очереди сообщение отбрасывается
+func (h *Hub) Publish(topic string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("⚠️  Error marshaling %s message: %v", topic, err)
+		return
+	}
+
+	select {
+	case h.publish <- publication{topic: topic, value: payload, payload: data}:
+	default:
+		atomic.AddUint64(&h.droppedBroadcasts, 1)
 		log.Println("⚠️  Broadcast channel full, dropping message")
 	}
 }
 
-func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
+// ClientCount — количество подключённых клиентов
+func (h *Hub) ClientCount() int {
+	return int(atomic.LoadInt64(&h.clientCount))
+}
+
+// DroppedBroadcasts — сколько сообщений отброшено из-за переполненного канала рассылки
+func (h *Hub) DroppedBroadcasts() uint64 {
+	return atomic.LoadUint64(&h.droppedBroadcasts)
+}
+
+// DroppedClients — сколько клиентов отключено из-за переполненного буфера отправки
+func (h *Hub) DroppedClients() uint64 {
+	return atomic.LoadUint64(&h.droppedClients)
+}
+
+// HandleWebSocket подключает клиента. Пока клиент не подписался, он ничего не получает.
+// authorize (может быть nil) проверяет доступ к топикам при подписке
+func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request, authorize Authorizer) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("❌ WebSocket upgrade error: %v", err)
 		return
 	}
+	conn.SetReadLimit(maxCommandSize)
 
 	client := &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan interface{}, 256),
+		hub:       hub,
+		conn:      conn,
+		send:      make(chan []byte, hub.sendBuffer),
+		authorize: authorize,
+		topics:    make(map[string]Subscription),
 	}
 
 	hub.register <- client
@@ -97,6 +352,17 @@ func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
 	go client.readPump()
 }
 
+// unsubscribe снимает подписку на топик; вызывается из горутины Hub.Run
+func (c *Client) unsubscribe(topic string) {
+	if sub, ok := c.topics[topic]; ok {
+		if sub != nil {
+			sub.Close()
+		}
+		delete(c.topics, topic)
+	}
+}
+
+// readPump читает команды подписки
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
@@ -104,42 +370,28 @@ func (c *Client) readPump() {
 	}()
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("⚠️  WebSocket error: %v", err)
 			}
 			break
 		}
+
+		var cmd Command
+		err = json.Unmarshal(data, &cmd)
+		c.hub.commands <- clientCommand{client: c, command: cmd, err: err}
 	}
 }
 
 func (c *Client) writePump() {
 	defer c.conn.Close()
 
-	for {
-		select {
-		case message, ok := <-c.send:
-			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			data, ok := message.([]byte)
-			if !ok {
-				// Try to marshal if it's not already bytes
-				var err error
-				data, err = json.Marshal(message)
-				if err != nil {
-					log.Printf("⚠️  Error marshaling message: %v", err)
-					continue
-				}
-			}
-
-			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
-				log.Printf("⚠️  Write error: %v", err)
-				return
-			}
+	for message := range c.send {
+		if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			log.Printf("⚠️  Write error: %v", err)
+			return
 		}
 	}
+	c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 }
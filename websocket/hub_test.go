@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/waplay/picoclaw-dashboard/pkg/config"
+)
+
+type testSubscription struct{ closed bool }
+
+func (s *testSubscription) Match(payload interface{}) bool { return true }
+func (s *testSubscription) Close()                         { s.closed = true }
+
+// newTestClient регистрирует клиента без соединения; вызывается вместо горутины Hub.Run
+func newTestClient(h *Hub, sendBuffer int) *Client {
+	c := &Client{hub: h, send: make(chan []byte, sendBuffer), topics: make(map[string]Subscription)}
+	h.clients[c] = true
+	return c
+}
+
+func TestHandleCommandAfterOverflow(t *testing.T) {
+	h := NewHub(config.Default())
+
+	// Первая ошибка заполняет буфер, вторая отключает клиента; дальше — ни паники
+	// на закрытом c.send, ни новых подписок
+	c := newTestClient(h, 1)
+	h.handleCommand(c, Command{Type: CommandSubscribe, Topics: []string{"a", "b", "c", TopicMetrics}})
+
+	if h.clients[c] {
+		t.Fatal("client with a full buffer is still registered")
+	}
+	if len(c.topics) != 0 {
+		t.Errorf("removed client has topics %v", c.topics)
+	}
+	if _, ok := <-c.send; !ok {
+		t.Error("first message was not delivered")
+	}
+	if _, ok := <-c.send; ok {
+		t.Error("send channel is not closed")
+	}
+
+	// Отключённому клиенту ничего не отправляется
+	h.deliverJSON(c, TypeError, "", ErrorPayload{Error: "late"})
+	h.handleCommand(c, Command{Type: "bogus"})
+}
+
+func TestHandleCommandClosesLateSubscription(t *testing.T) {
+	h := NewHub(config.Default())
+	c := newTestClient(h, 4)
+
+	// Клиент отключается, пока создаётся подписка
+	sub := &testSubscription{}
+	h.Handle(TopicLogs, func(params json.RawMessage) (Subscription, error) {
+		h.remove(c)
+		return sub, nil
+	})
+	h.handleCommand(c, Command{Type: CommandSubscribe, Topics: []string{TopicLogs}})
+
+	if !sub.closed {
+		t.Error("subscription created for a removed client is not closed")
+	}
+	if len(c.topics) != 0 {
+		t.Errorf("removed client has topics %v", c.topics)
+	}
+}
+
+func TestHandleCommandSubscribe(t *testing.T) {
+	h := NewHub(config.Default())
+	sub := &testSubscription{}
+	h.Handle(TopicLogs, func(params json.RawMessage) (Subscription, error) { return sub, nil })
+
+	c := newTestClient(h, 4)
+	h.handleCommand(c, Command{Type: CommandSubscribe, Topics: []string{TopicLogs, TopicMetrics}})
+	if len(c.topics) != 2 || c.topics[TopicLogs] != sub {
+		t.Fatalf("topics = %v", c.topics)
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(<-c.send, &env); err != nil || env.Type != TypeSubscribed || env.Seq != 1 {
+		t.Errorf("reply = %+v, %v", env, err)
+	}
+
+	h.handleCommand(c, Command{Type: CommandUnsubscribe, Topics: []string{TopicLogs}})
+	if !sub.closed || len(c.topics) != 1 {
+		t.Errorf("after unsubscribe: closed=%v topics=%v", sub.closed, c.topics)
+	}
+}